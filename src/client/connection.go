@@ -0,0 +1,197 @@
+package main
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/internal/wire"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand/v2"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// If the connection drops, the client keeps trying to reconnect, waiting longer after each failure.
+// It reconnects with the session token the server gave it, so it gets its own ship back.
+
+const (
+	minReconnectWait = 250 * time.Millisecond
+	maxReconnectWait = 8 * time.Second
+)
+
+var serverURL url.URL
+var protocols []string
+
+// session is the token from the server to reconnect with
+var session string
+
+var socket WSConn
+
+// socketMu makes sure only one goroutine writes to the socket at a time
+var socketMu sync.Mutex
+
+// quitting is set when the client is closing the connection on purpose, so it doesn't reconnect
+var quitting atomic.Bool
+
+var errNotConnected = errors.New("not connected")
+
+func sendMessage(msg shared_structs.ClientMessage) error {
+	msgBytes, _ := json.Marshal(msg)
+	socketMu.Lock()
+	defer socketMu.Unlock()
+	if socket == nil {
+		return errNotConnected
+	}
+	return socket.WriteMessage(websocket.TextMessage, msgBytes)
+}
+
+// connect dials the server, and resumes the session if there is one
+func connect() error {
+	u := serverURL
+	if session != "" {
+		u.RawQuery = url.Values{"session": {session}}.Encode()
+	}
+	slog.Debug("connecting to " + serverURL.String())
+	conn, err := DialWS(u.String(), protocols)
+	if err != nil {
+		return err
+	}
+	// the server doesn't spawn us until it has checked who we are
+	hello, _ := json.Marshal(shared_structs.ClientMessage{Type: constants.HelloMessage, Hello: &shared_structs.Hello{
+		Version: wire.Version,
+		Name:    userConfig.Name,
+		Skin:    userConfig.Skin,
+	}})
+	err = conn.WriteMessage(websocket.TextMessage, hello)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	socketMu.Lock()
+	socket = conn
+	socketMu.Unlock()
+	return nil
+}
+
+// reconnect keeps trying to connect until it works
+func reconnect() {
+	socketMu.Lock()
+	if socket != nil {
+		socket.Close()
+		socket = nil
+	}
+	socketMu.Unlock()
+
+	// the new connection starts from a full snapshot, and maybe a different server
+	mu.Lock()
+	clear(snapshots)
+	renderHistory = nil
+	pendingInputs = nil
+	predictionReady = false
+	mu.Unlock()
+
+	wait := minReconnectWait
+	for {
+		slog.Info("reconnecting in " + wait.String())
+		time.Sleep(wait)
+		err := connect()
+		if err == nil {
+			slog.Info("reconnected")
+			return
+		}
+		slog.Error("reconnect failed: " + err.Error())
+		wait = min(wait*2, maxReconnectWait)
+	}
+}
+
+// readLoop reads snapshots from the server until the client quits, reconnecting whenever the connection drops
+func readLoop(done chan struct{}) {
+	defer close(done)
+	for {
+		socketMu.Lock()
+		conn := socket
+		socketMu.Unlock()
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			if quitting.Load() {
+				return
+			}
+			// reconnecting won't help if the server didn't like our hello
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) && (closeErr.Code == constants.CloseBadVersion || closeErr.Code == constants.CloseBadHello) {
+				log.Fatal("The server turned us away: " + closeErr.Text)
+			}
+			slog.Error("Websocket read error: " + err.Error())
+			reconnect()
+			continue
+		}
+		handleMessage(messageType, message)
+	}
+}
+
+func handleMessage(messageType int, message []byte) {
+	var newState shared_structs.WorldData
+	if messageType == websocket.BinaryMessage {
+		decoded, err := wire.Decode(message)
+		if err != nil {
+			slog.Error("decode: " + err.Error())
+			return
+		}
+		newState = *decoded
+	} else {
+		slog.Debug(string(bytes.TrimSpace(message)))
+		err := json.Unmarshal(message, &newState)
+		if err != nil {
+			slog.Error("unmarshal: " + err.Error())
+			return
+		}
+	}
+	mu.Lock()
+	state, ok := applySnapshot(&newState)
+	resync := !ok && newState.Baseline != missingBaseline
+	if resync {
+		missingBaseline = newState.Baseline
+	}
+	if ok {
+		gameData = newState.GameData
+		worldMap = state
+		recordSnapshot(newState.Tick, state)
+		recordServerShip(newState.GameData.Ship)
+		if newState.GameData.Session != "" {
+			session = newState.GameData.Session
+		}
+		if len(newState.Scoreboard) > 0 {
+			scoreboard = newState.Scoreboard
+		}
+		if newState.Match != nil {
+			match = newState.Match
+		}
+	}
+	mu.Unlock()
+	if ok {
+		err := sendMessage(shared_structs.ClientMessage{Type: constants.AckMessage, Ack: newState.Tick})
+		if err != nil {
+			slog.Debug("ack send error: " + err.Error())
+		}
+	}
+	if resync {
+		// otherwise the server keeps sending deltas from the missing baseline until it forgets it
+		err := sendMessage(shared_structs.ClientMessage{Type: constants.ResyncMessage})
+		if err != nil {
+			slog.Debug("resync send error: " + err.Error())
+		}
+	}
+}
+
+// randomName is the name players get until they pick one
+func randomName() string {
+	return fmt.Sprintf("Pilot-%04d", rand.IntN(10000))
+}
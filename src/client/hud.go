@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	healthBarWidth  = 300
+	healthBarHeight = 20
+	healthBarMargin = 20
+)
+
+var (
+	healthBarBackground = color.RGBA{R: 60, G: 20, B: 20, A: 200}
+	healthBarFill       = color.RGBA{R: 40, G: 200, B: 60, A: 230}
+)
+
+// drawHealth draws the player's health bar in the bottom left corner, or the respawn countdown while they are dead.
+// The caller must hold mu.
+func drawHealth(screen *ebiten.Image) {
+	if gameData.MaxHealth == 0 {
+		return
+	}
+	x := float32(healthBarMargin)
+	y := float32(screenHeight - healthBarMargin - healthBarHeight)
+	if gameData.Health <= 0 {
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("Destroyed! Respawning in %.1f", gameData.RespawnIn), int(x), int(y))
+		return
+	}
+	fill := float32(gameData.Health) / float32(gameData.MaxHealth)
+	vector.FillRect(screen, x, y, healthBarWidth, healthBarHeight, healthBarBackground, false)
+	vector.FillRect(screen, x, y, healthBarWidth*fill, healthBarHeight, healthBarFill, false)
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%d/%d", gameData.Health, gameData.MaxHealth), int(x)+4, int(y)+2)
+}
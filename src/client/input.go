@@ -0,0 +1,173 @@
+package main
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"log/slog"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Bindings are stored in the user config by action name, so they can be edited by hand
+var actionNames = map[string]shared_structs.Actions{
+	"thrust":     shared_structs.ActionThrust,
+	"turn_left":  shared_structs.ActionTurnLeft,
+	"turn_right": shared_structs.ActionTurnRight,
+	"brake":      shared_structs.ActionBrake,
+	"fire":       shared_structs.ActionFire,
+	"bomb":       shared_structs.ActionBomb,
+	"portal":     shared_structs.ActionPortal,
+}
+
+// Keys are stored by their ebiten names, like "W", "ArrowUp" or "Space"
+func defaultKeyBindings() map[string][]string {
+	return map[string][]string{
+		"thrust":     {"W"},
+		"turn_left":  {"A"},
+		"turn_right": {"D"},
+		"brake":      {"S"},
+		"fire":       {"E"},
+		"bomb":       {"B"},
+		"portal":     {"P"},
+	}
+}
+
+// gamepadButtonNames names the buttons of a standard layout gamepad, using xbox names
+// https://www.w3.org/TR/gamepad/#remapping
+var gamepadButtonNames = map[string]ebiten.StandardGamepadButton{
+	"a":          ebiten.StandardGamepadButtonRightBottom,
+	"b":          ebiten.StandardGamepadButtonRightRight,
+	"x":          ebiten.StandardGamepadButtonRightLeft,
+	"y":          ebiten.StandardGamepadButtonRightTop,
+	"lb":         ebiten.StandardGamepadButtonFrontTopLeft,
+	"rb":         ebiten.StandardGamepadButtonFrontTopRight,
+	"lt":         ebiten.StandardGamepadButtonFrontBottomLeft,
+	"rt":         ebiten.StandardGamepadButtonFrontBottomRight,
+	"back":       ebiten.StandardGamepadButtonCenterLeft,
+	"start":      ebiten.StandardGamepadButtonCenterRight,
+	"ls":         ebiten.StandardGamepadButtonLeftStick,
+	"rs":         ebiten.StandardGamepadButtonRightStick,
+	"dpad_up":    ebiten.StandardGamepadButtonLeftTop,
+	"dpad_down":  ebiten.StandardGamepadButtonLeftBottom,
+	"dpad_left":  ebiten.StandardGamepadButtonLeftLeft,
+	"dpad_right": ebiten.StandardGamepadButtonLeftRight,
+}
+
+func defaultGamepadBindings() map[string][]string {
+	return map[string][]string{
+		"thrust":     {"rt", "a"},
+		"turn_left":  {"dpad_left"},
+		"turn_right": {"dpad_right"},
+		"brake":      {"lt"},
+		"fire":       {"rb", "x"},
+		"bomb":       {"lb", "b"},
+		"portal":     {"y"},
+	}
+}
+
+// how far the left stick has to be pushed before it counts, if the config doesn't say
+const defaultGamepadDeadzone = 0.3
+
+// boundKeys and boundButtons are the bindings from the user config, checked over and ready to use
+var boundKeys = map[ebiten.Key]shared_structs.Actions{}
+var boundButtons = map[ebiten.StandardGamepadButton]shared_structs.Actions{}
+
+// applyBindings reads the bindings out of the user config. Anything that doesn't make sense is logged and skipped.
+func applyBindings() {
+	boundKeys = map[ebiten.Key]shared_structs.Actions{}
+	boundButtons = map[ebiten.StandardGamepadButton]shared_structs.Actions{}
+	for name, keys := range userConfig.KeyBindings {
+		action, ok := actionNames[name]
+		if !ok {
+			slog.Warn("unknown action in key bindings: " + name)
+			continue
+		}
+		for _, keyName := range keys {
+			var key ebiten.Key
+			err := key.UnmarshalText([]byte(keyName))
+			if err != nil {
+				slog.Warn("unknown key in bindings: " + keyName)
+				continue
+			}
+			boundKeys[key] |= action
+		}
+	}
+	for name, buttons := range userConfig.GamepadBindings {
+		action, ok := actionNames[name]
+		if !ok {
+			slog.Warn("unknown action in gamepad bindings: " + name)
+			continue
+		}
+		for _, buttonName := range buttons {
+			button, ok := gamepadButtonNames[buttonName]
+			if !ok {
+				slog.Warn("unknown gamepad button in bindings: " + buttonName)
+				continue
+			}
+			boundButtons[button] |= action
+		}
+	}
+	if userConfig.GamepadDeadzone <= 0 || userConfig.GamepadDeadzone >= 1 {
+		userConfig.GamepadDeadzone = defaultGamepadDeadzone
+	}
+}
+
+// readActions returns the actions held on the keyboard and every connected gamepad
+func readActions() shared_structs.Actions {
+	var actions shared_structs.Actions
+	for key, action := range boundKeys {
+		if ebiten.IsKeyPressed(key) {
+			actions |= action
+		}
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		for button, action := range boundButtons {
+			if ebiten.IsStandardGamepadButtonPressed(id, button) {
+				actions |= action
+			}
+		}
+		if userConfig.GamepadStickSteering {
+			// the left stick turns, and pushing it up thrusts
+			deadzone := userConfig.GamepadDeadzone
+			x := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal)
+			y := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical)
+			if x < -deadzone {
+				actions |= shared_structs.ActionTurnLeft
+			} else if x > deadzone {
+				actions |= shared_structs.ActionTurnRight
+			}
+			if y < -deadzone {
+				actions |= shared_structs.ActionThrust
+			}
+		}
+	}
+	return actions
+}
+
+// how many commands are sent in each message. Resending a few covers for a message that goes missing.
+const resentInputs = 3
+
+// recentInputs are the last few commands, oldest first
+var recentInputs []shared_structs.InputCommand
+
+// estimatedServerTick is the tick the server is probably on right now. The caller must hold mu.
+func estimatedServerTick() uint64 {
+	return latestTick + uint64(time.Since(latestArrival).Seconds()*tickRate)
+}
+
+// nextInputMessage records a tick of actions and returns the message to send for it
+func nextInputMessage(seq uint64, actions shared_structs.Actions) shared_structs.ClientMessage {
+	recentInputs = append(recentInputs, shared_structs.InputCommand{
+		Seq:     seq,
+		Tick:    estimatedServerTick(),
+		Actions: actions,
+	})
+	if len(recentInputs) > resentInputs {
+		recentInputs = recentInputs[len(recentInputs)-resentInputs:]
+	}
+	return shared_structs.ClientMessage{Type: constants.InputMessage, Inputs: recentInputs}
+}
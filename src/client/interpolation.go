@@ -0,0 +1,136 @@
+package main
+
+import (
+	"Geomyidae/internal/shared_structs"
+	"math"
+	"time"
+)
+
+// Objects are drawn a little in the past, between the two snapshots on either side of renderTick,
+// so that they move smoothly instead of jumping every time a snapshot arrives.
+
+// interpolationDelay is how many ticks behind the newest snapshot objects are drawn.
+// It has to cover the gap between snapshots plus some network jitter.
+const interpolationDelay = 3.0
+
+// maxExtrapolation is how many ticks past the newest snapshot objects keep moving if snapshots are late.
+// After that they stop where they are predicted to be until a snapshot shows up.
+const maxExtrapolation = 10.0
+
+// how many snapshots are kept for drawing
+const renderHistorySize = 32
+
+type renderSnapshot struct {
+	tick    uint64
+	objects map[string]*shared_structs.GameObject
+}
+
+// interpolate can be turned off to see exactly what the server sent
+var interpolate = true
+
+// renderHistory is the most recent snapshots, oldest first
+var renderHistory []renderSnapshot
+
+// renderTick is the server tick being drawn. It is fractional, since there are usually several frames per tick.
+var renderTick float64
+var lastFrame time.Time
+var tickRate = 50.0
+
+// latestTick and latestArrival are used to estimate which tick the server is on right now
+var latestTick uint64
+var latestArrival time.Time
+
+// recordSnapshot adds a snapshot to renderHistory. The caller must hold mu.
+func recordSnapshot(tick uint64, objects map[string]*shared_structs.GameObject) {
+	if len(renderHistory) > 0 && tick <= renderHistory[len(renderHistory)-1].tick {
+		return // out of order
+	}
+	renderHistory = append(renderHistory, renderSnapshot{tick, objects})
+	if len(renderHistory) > renderHistorySize {
+		renderHistory = renderHistory[len(renderHistory)-renderHistorySize:]
+	}
+	if gameData.TickRate > 0 {
+		tickRate = float64(gameData.TickRate)
+	}
+	latestTick = tick
+	latestArrival = time.Now()
+}
+
+// advanceRenderClock moves renderTick forward by the time since the last frame,
+// and nudges it toward where it should be so that it doesn't drift away from the server
+func advanceRenderClock() {
+	now := time.Now()
+	if lastFrame.IsZero() || len(renderHistory) == 0 {
+		lastFrame = now
+		return
+	}
+	renderTick += now.Sub(lastFrame).Seconds() * tickRate
+	lastFrame = now
+
+	target := float64(latestTick) + now.Sub(latestArrival).Seconds()*tickRate - interpolationDelay
+	if math.Abs(target-renderTick) > tickRate {
+		// more than a second off, probably just connected, so jump instead of catching up
+		renderTick = target
+	} else {
+		renderTick += (target - renderTick) * 0.1
+	}
+}
+
+// renderedWorld returns the objects to draw this frame. The caller must hold mu.
+func renderedWorld() map[string]*shared_structs.GameObject {
+	if !interpolate || len(renderHistory) < 2 {
+		return worldMap
+	}
+
+	latest := renderHistory[len(renderHistory)-1]
+	rendered := make(map[string]*shared_structs.GameObject, len(latest.objects))
+	for key, object := range latest.objects {
+		rendered[key] = objectAt(key, object)
+	}
+	return rendered
+}
+
+// objectAt returns a copy of object positioned at renderTick
+func objectAt(key string, object *shared_structs.GameObject) *shared_structs.GameObject {
+	// find the snapshots on either side of renderTick that have this object
+	var before, after *shared_structs.GameObject
+	var beforeTick, afterTick float64
+	for i := len(renderHistory) - 1; i >= 0; i-- {
+		snap := renderHistory[i]
+		obj, ok := snap.objects[key]
+		if !ok {
+			break
+		}
+		if float64(snap.tick) > renderTick || after == nil {
+			after, afterTick = obj, float64(snap.tick)
+			continue
+		}
+		before, beforeTick = obj, float64(snap.tick)
+		break
+	}
+
+	if before == nil {
+		// the object hasn't been around since renderTick, so draw it where it first showed up
+		return after
+	}
+
+	t := (renderTick - beforeTick) / (afterTick - beforeTick)
+	t = math.Min(t, 1+maxExtrapolation/(afterTick-beforeTick))
+
+	drawn := *object
+	drawn.X = int(math.Round(lerp(float64(before.X), float64(after.X), t)))
+	drawn.Y = int(math.Round(lerp(float64(before.Y), float64(after.Y), t)))
+	drawn.Angle = shared_structs.RoundedFloat2(lerpAngle(float64(before.Angle), float64(after.Angle), t))
+	return &drawn
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// lerpAngle is lerp for angles in radians. It turns the short way round, so a ship spinning past ±π doesn't spin
+// all the way back the other way between two snapshots.
+func lerpAngle(a, b, t float64) float64 {
+	diff := math.Remainder(b-a, 2*math.Pi) // in [-π, π]
+	return a + diff*t
+}
@@ -2,11 +2,14 @@ package main
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"image"
 	"log"
 	"log/slog"
+	"maps"
 
 	"math"
 	"net/url"
@@ -18,9 +21,11 @@ import (
 
 	assets "Geomyidae"
 
+	"Geomyidae/internal/constants"
 	"Geomyidae/internal/shared_structs"
+	"Geomyidae/internal/ship"
+	"Geomyidae/internal/wire"
 
-	"github.com/gorilla/websocket"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -30,8 +35,9 @@ import (
 )
 
 const (
-	screenWidth  = 1920
-	screenHeight = 1080
+	screenWidth    = constants.ScreenWidth
+	screenHeight   = constants.ScreenHeight
+	metersToPixels = constants.MetersToPixels
 )
 
 var sprites map[string]*ebiten.Image
@@ -41,7 +47,6 @@ type Game struct{}
 var worldMap map[string]*shared_structs.GameObject
 var gameData shared_structs.GameData
 var mu sync.Mutex
-var oldKeys shared_structs.KeyStruct
 
 type UserConfig struct {
 	ConfigDir       string `json:"config_dir"`
@@ -51,6 +56,14 @@ type UserConfig struct {
 	WindowSizeX     int    `json:"window_size_x"`
 	WindowSizeY     int    `json:"window_size_y"`
 	IsFullscreen    bool   `json:"is_fullscreen"`
+	// Bindings map action names to keys or gamepad buttons, see input.go for the names
+	KeyBindings          map[string][]string `json:"key_bindings"`
+	GamepadBindings      map[string][]string `json:"gamepad_bindings"`
+	GamepadStickSteering bool                `json:"gamepad_stick_steering"`
+	GamepadDeadzone      float64             `json:"gamepad_deadzone"`
+	// Name and Skin are sent to the server when joining, see ship.Skins for the skins
+	Name string `json:"name"`
+	Skin string `json:"skin"`
 }
 
 var userConfig UserConfig
@@ -82,8 +95,6 @@ func (g *Game) Update() error {
 		}
 	}
 
-	msg := shared_structs.KeyStruct{}
-
 	for i, ekey := range debounceKeys {
 		if ekey > 0 {
 			debounceKeys[i]--
@@ -108,19 +119,43 @@ func (g *Game) Update() error {
 				debounceKeys[ekey] = 10
 				ebiten.SetFullscreen(!ebiten.IsFullscreen())
 			}
-		} else {
-			msg.Keys = append(msg.Keys, ekey.String())
+		} else if ekey == ebiten.KeyF2 {
+			if debounceKeys[ekey] == 0 {
+				debounceKeys[ekey] = 10
+				mu.Lock()
+				interpolate = !interpolate
+				mu.Unlock()
+			}
+		} else if ekey == ebiten.KeyF3 {
+			if debounceKeys[ekey] == 0 {
+				debounceKeys[ekey] = 10
+				mu.Lock()
+				predict = !predict
+				mu.Unlock()
+			}
+		} else if ekey == ebiten.KeyTab {
+			if debounceKeys[ekey] == 0 {
+				debounceKeys[ekey] = 10
+				mu.Lock()
+				showScoreboard = !showScoreboard
+				mu.Unlock()
+			}
 		}
 	}
-	if slices.Equal(msg.Keys, oldKeys.Keys) {
-		return nil
+	actions := readActions()
+	// every tick is sent, even if nothing changed, since each one is predicted and has to be matched up with the server
+	mu.Lock()
+	if gameData.TickRate > 0 && ebiten.TPS() != gameData.TickRate {
+		ebiten.SetTPS(gameData.TickRate)
 	}
-	oldKeys = msg
-	msgBytes, _ := json.Marshal(msg)
+	seq := predictTick(ship.ControlsFor(actions), 1/float64(ebiten.TPS()))
+	msg := nextInputMessage(seq, actions)
+	mu.Unlock()
 
-	err := socket.WriteMessage(websocket.TextMessage, msgBytes)
+	err := sendMessage(msg)
 	if err != nil {
-		log.Fatal("Websocket send error:", err.Error())
+		// the read loop notices the connection is gone and reconnects
+		slog.Debug("Websocket send error: " + err.Error())
 	}
 
 	return nil
@@ -128,13 +163,27 @@ func (g *Game) Update() error {
 
 var cameraX int
 var cameraY int
-var socket WSConn
 
 func (g *Game) Draw(screen *ebiten.Image) {
 	var myPlayerObject shared_structs.GameObject
 	mu.Lock()
 	defer mu.Unlock()
-	for _, object := range worldMap {
+	advanceRenderClock()
+	rendered := renderedWorld()
+	if object, ok := rendered[gameData.PlayerUUID]; ok {
+		if rendered[gameData.PlayerUUID] == worldMap[gameData.PlayerUUID] {
+			// don't overwrite the real state with the prediction
+			rendered = maps.Clone(rendered)
+		}
+		object = predictedObject(object)
+		rendered[gameData.PlayerUUID] = object
+		cameraX, cameraY = object.X-screenWidth/2, object.Y-screenHeight/2
+	}
+	// draw in depth order, so tiles on layers above the ships cover them
+	drawOrder := slices.SortedStableFunc(maps.Values(rendered), func(a, b *shared_structs.GameObject) int {
+		return cmp.Compare(a.Depth, b.Depth)
+	})
+	for _, object := range drawOrder {
 		if object.UUID == gameData.PlayerUUID {
 			myPlayerObject = *object
 		}
@@ -150,8 +199,13 @@ func (g *Game) Draw(screen *ebiten.Image) {
 			op.GeoM.Rotate(math.Pi / 2)
 		}
 		op.GeoM.Rotate(float64(object.Angle))
-		op.GeoM.Translate(float64(object.X-cameraX), float64(object.Y-cameraY))
+		// parallax layers lag behind the camera
+		op.GeoM.Translate(float64(object.X-cameraX)+float64(cameraX)*float64(object.ParallaxX), float64(object.Y-cameraY)+float64(cameraY)*float64(object.ParallaxY))
 		screen.DrawImage(sprites[object.Sprite].SubImage(image.Rect(object.SpriteOffsetX, object.SpriteOffsetY, object.SpriteOffsetX+object.SpriteWidth, object.SpriteOffsetY+object.SpriteHeight)).(*ebiten.Image), op)
+		if object.Name != "" {
+			// the debug font is 6 pixels wide and 16 tall
+			ebitenutil.DebugPrintAt(screen, object.Name, object.X-cameraX-len(object.Name)*3, object.Y-cameraY-object.SpriteHeight/2-20)
+		}
 	}
 
 	// Client side UI elements
@@ -165,8 +219,11 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		hudOverlay.SetScaleY(10)
 		hudOverlay.Draw(screen)
 	}
+	drawHealth(screen)
+	drawScoreboard(screen)
+	drawMatch(screen)
 
-	ebitenutil.DebugPrint(screen, "Camera position: "+fmt.Sprintf("%d, %d | Goroutines: %v\np - Toggle Portal\nf or F11 - Toggle Fullscreen", cameraX, cameraY, runtime.NumGoroutine()))
+	ebitenutil.DebugPrint(screen, "Camera position: "+fmt.Sprintf("%d, %d | Goroutines: %v | Interpolation: %v | Prediction: %v\np - Toggle Portal\nf or F11 - Toggle Fullscreen\nF2 - Toggle Interpolation\nF3 - Toggle Prediction\nTab - Toggle Scoreboard", cameraX, cameraY, runtime.NumGoroutine(), interpolate, predict))
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
@@ -175,9 +232,15 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
 
 // assets are embedded in package "assets"
 
+var useJSON = flag.Bool("json", false, "receive snapshots as JSON instead of binary, for debugging")
+var nameFlag = flag.String("name", "", "the name to show above your ship, instead of the one in the config file")
+var skinFlag = flag.String("skin", "", "the ship skin to fly, instead of the one in the config file")
+
 func main() {
+	flag.Parse()
 	// slog.SetLogLoggerLevel(slog.LevelDebug)
 	worldMap = make(map[string]*shared_structs.GameObject)
+	initPrediction()
 
 	platformPackData, err := assets.FS.ReadFile("assets/img/platformerPack_industrial_tilesheet_64x64.png")
 	if err != nil {
@@ -210,63 +273,13 @@ func main() {
 	sprites["spaceShooterRedux"] = ebiten.NewImageFromImage(spaceShooterReduxImg)
 	sprites["portalMask"] = ebiten.NewImageFromImage(portalMaskImg)
 
-	// Connect to WebSocket server
-	u := url.URL{Scheme: "ws", Host: "localhost:8080", Path: "/ws"}
-	// For website in "production":
-	// u := url.URL{Scheme: "wss", Host: "geomyidae-server.ekpyroticfrood.net", Path: "/ws"}
-	slog.Debug("connecting to %s", u.String())
-
-	conn, err := DialWS(u.String())
-	if err != nil {
-		log.Fatal("dial:", err)
-	}
-	defer func(c WSConn) {
-		err := c.Close()
-		if err != nil {
-			slog.Error(err.Error())
-		}
-	}(conn)
-
-	socket = conn
-
-	done := make(chan struct{})
-
-	go func() {
-		defer close(done)
-		for {
-			_, message, err := conn.ReadMessage()
-			if err != nil {
-				log.Fatal("Websocket read error:", err)
-			}
-			slog.Debug(string(bytes.TrimSpace(message)))
-			var newState shared_structs.WorldData
-			err = json.Unmarshal(message, &newState)
-			if err != nil {
-				slog.Error("unmarshal:", err)
-			}
-			mu.Lock()
-			gameData = newState.GameData
-			for _, object := range newState.Objects {
-				key := object.UUID
-				if key == gameData.PlayerUUID {
-					cameraX, cameraY = object.X-screenWidth/2, object.Y-screenHeight/2
-				}
-				if object.Delete {
-					delete(worldMap, key)
-				} else {
-					worldMap[key] = &object
-				}
-			}
-			mu.Unlock()
-		}
-	}()
-
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
-
-	go handleChannels(done, interrupt, conn)
-
 	// Load user config data
+	userConfig.KeyBindings = defaultKeyBindings()
+	userConfig.GamepadBindings = defaultGamepadBindings()
+	userConfig.GamepadStickSteering = true
+	userConfig.GamepadDeadzone = defaultGamepadDeadzone
+	userConfig.Name = randomName()
+	userConfig.Skin = ship.DefaultSkin
 	userConfig.ConfigDir, err = os.UserConfigDir()
 	if err != nil {
 		slog.Debug("Could not get user config dir: %v", err)
@@ -305,6 +318,48 @@ func main() {
 		}
 		slog.Debug("User config file path:", userConfig.ConfigPath)
 	}
+	applyBindings()
+	if *nameFlag != "" {
+		userConfig.Name = *nameFlag
+	}
+	if *skinFlag != "" {
+		userConfig.Skin = *skinFlag
+	}
+
+	// Connect to WebSocket server
+	serverURL = url.URL{Scheme: "ws", Host: "localhost:8080", Path: "/ws"}
+	// For website in "production":
+	// serverURL = url.URL{Scheme: "wss", Host: "geomyidae-server.ekpyroticfrood.net", Path: "/ws"}
+
+	protocols = []string{wire.BinaryProtocol}
+	if *useJSON {
+		protocols = []string{wire.JSONProtocol}
+	}
+	err = connect()
+	if err != nil {
+		log.Fatal("dial:", err)
+	}
+	defer func() {
+		quitting.Store(true)
+		socketMu.Lock()
+		defer socketMu.Unlock()
+		if socket == nil {
+			return
+		}
+		err := socket.Close()
+		if err != nil {
+			slog.Error(err.Error())
+		}
+	}()
+
+	done := make(chan struct{})
+
+	go readLoop(done)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	go handleChannels(done, interrupt)
 
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 	ebiten.SetWindowSize(screenWidth, screenHeight)
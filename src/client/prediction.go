@@ -0,0 +1,101 @@
+package main
+
+import (
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/internal/ship"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// The local ship is predicted: controls move a local copy of the ship as soon as they are pressed,
+// instead of waiting for the server to send the ship's new position back. When the server's state for the ship
+// arrives, the local copy is reset to it and every input the server hasn't applied yet is replayed on top.
+// The local copy only knows about the ship, so it flies through walls until the server corrects it.
+
+// predict can be turned off to see where the server has the ship
+var predict = true
+
+var predictionSpace *cp.Space
+var predictedShip *cp.Body
+
+// inputSeq is the sequence number of the last input sent
+var inputSeq uint64
+
+type pendingInput struct {
+	seq      uint64
+	controls ship.Controls
+}
+
+// pendingInputs are inputs that have been predicted but not yet applied by the server, oldest first
+var pendingInputs []pendingInput
+
+// if the server stops applying inputs, don't keep them forever
+const maxPendingInputs = 256
+
+// serverShip is the latest state of the ship from the server, and serverShipFresh is true until it is reconciled
+var serverShip shared_structs.ShipState
+var serverShipFresh bool
+var predictionReady bool
+
+func initPrediction() {
+	predictionSpace = cp.NewSpace()
+	body, shape := ship.NewBody()
+	predictionSpace.AddBody(body)
+	predictionSpace.AddShape(shape)
+	predictedShip = body
+}
+
+// recordServerShip stores the ship state from a snapshot. The caller must hold mu.
+func recordServerShip(state shared_structs.ShipState) {
+	serverShip = state
+	serverShipFresh = true
+}
+
+// predictTick corrects the prediction if the server has sent a new state, then predicts one tick of controls.
+// It returns the sequence number to send the controls with. The caller must hold mu.
+func predictTick(controls ship.Controls, deltaTime float64) uint64 {
+	if serverShipFresh {
+		reconcile(deltaTime)
+	}
+	inputSeq++
+	pendingInputs = append(pendingInputs, pendingInput{inputSeq, controls})
+	if len(pendingInputs) > maxPendingInputs {
+		pendingInputs = pendingInputs[len(pendingInputs)-maxPendingInputs:]
+	}
+	ship.Fly(predictedShip, controls, deltaTime)
+	predictionSpace.Step(deltaTime)
+	return inputSeq
+}
+
+// reconcile resets the predicted ship to the server's state and replays the inputs the server hasn't seen yet
+func reconcile(deltaTime float64) {
+	serverShipFresh = false
+	predictionReady = true
+	predictedShip.SetPosition(cp.Vector{X: serverShip.X, Y: serverShip.Y})
+	predictedShip.SetVelocity(serverShip.VX, serverShip.VY)
+	predictedShip.SetAngle(serverShip.Angle)
+	predictedShip.SetAngularVelocity(serverShip.AngularVelocity)
+
+	applied := 0
+	for applied < len(pendingInputs) && pendingInputs[applied].seq <= serverShip.Seq {
+		applied++
+	}
+	pendingInputs = pendingInputs[applied:]
+	for _, input := range pendingInputs {
+		ship.Fly(predictedShip, input.controls, deltaTime)
+		predictionSpace.Step(deltaTime)
+	}
+}
+
+// predictedObject returns a copy of the player's object moved to the predicted position
+func predictedObject(object *shared_structs.GameObject) *shared_structs.GameObject {
+	if !predict || !predictionReady {
+		return object
+	}
+	pos := predictedShip.Position()
+	drawn := *object
+	drawn.X = int(pos.X * metersToPixels)
+	drawn.Y = int(pos.Y * metersToPixels)
+	drawn.Angle = shared_structs.RoundedFloat2(predictedShip.Angle())
+	return &drawn
+}
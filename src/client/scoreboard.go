@@ -0,0 +1,89 @@
+package main
+
+import (
+	"Geomyidae/internal/shared_structs"
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// scoreboard and match are the latest ones the server pushed
+var scoreboard []shared_structs.PlayerScore
+var match *shared_structs.MatchState
+
+// showScoreboard is toggled with Tab
+var showScoreboard bool
+
+var scoreboardBackground = color.RGBA{R: 0, G: 0, B: 0, A: 180}
+
+const scoreboardRowHeight = 16
+
+// drawScoreboard draws the scoreboard in the middle of the screen. The caller must hold mu.
+func drawScoreboard(screen *ebiten.Image) {
+	if !showScoreboard || len(scoreboard) == 0 {
+		return
+	}
+	teams := match != nil && len(match.Teams) > 1
+	lines := []string{fmt.Sprintf("%-16s %5s %6s %7s %7s %9s %8s %9s", "Name", "Kills", "Deaths", "Assists", "Turrets", "Accuracy", "Pickups", "Time")}
+	if teams {
+		lines[0] += fmt.Sprintf(" %-6s", "Team")
+	}
+	for _, score := range scoreboard {
+		accuracy := "-"
+		if score.ShotsFired > 0 {
+			accuracy = fmt.Sprintf("%d%%", score.ShotsHit*100/score.ShotsFired)
+		}
+		name := score.Name
+		if score.ID == gameData.PlayerUUID {
+			name = "> " + name
+		}
+		line := fmt.Sprintf("%-16s %5d %6d %7d %7d %9s %8d %8.0fs",
+			name, score.Kills, score.Deaths, score.Assists, score.Turrets, accuracy, score.Pickups, score.TimeAlive)
+		if teams {
+			line += fmt.Sprintf(" %-6s", score.Team)
+		}
+		lines = append(lines, line)
+	}
+	// the debug font is 6 pixels wide
+	width := len(lines[0])*6 + 20
+	height := len(lines)*scoreboardRowHeight + 20
+	x := (screenWidth - width) / 2
+	y := (screenHeight - height) / 3
+	vector.FillRect(screen, float32(x), float32(y), float32(width), float32(height), scoreboardBackground, false)
+	ebitenutil.DebugPrintAt(screen, strings.Join(lines, "\n"), x+10, y+10)
+}
+
+// drawMatch shows how the round is going at the top of the screen, and the result in the middle once it is over.
+// The caller must hold mu.
+func drawMatch(screen *ebiten.Image) {
+	if match == nil {
+		return
+	}
+	status := fmt.Sprintf("%v | Round %d", match.Mode, match.Round)
+	if match.Map != "" {
+		status = match.Map + " | " + status
+	}
+	if match.TimeLeft > 0 {
+		left := int(match.TimeLeft)
+		status += fmt.Sprintf(" | %d:%02d left", left/60, left%60)
+	}
+	for _, team := range match.Teams {
+		status += fmt.Sprintf(" | %v %d", team.Team, team.Score)
+	}
+	ebitenutil.DebugPrintAt(screen, status, (screenWidth-len(status)*6)/2, 10)
+
+	if match.Result != "" {
+		result := fmt.Sprintf("%v\nNext round in %.0f", match.Result, float64(match.NextRound))
+		if match.NextMap != "" {
+			result = fmt.Sprintf("%v\nNext up: %v in %.0f", match.Result, match.NextMap, float64(match.NextRound))
+		}
+		width := max(len(match.Result), len(match.NextMap)+16, 20)*6 + 20
+		x, y := (screenWidth-width)/2, screenHeight/4
+		vector.FillRect(screen, float32(x), float32(y), float32(width), 2*scoreboardRowHeight+20, scoreboardBackground, false)
+		ebitenutil.DebugPrintAt(screen, result, x+10, y+10)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"Geomyidae/internal/shared_structs"
+	"maps"
+	"slices"
+)
+
+// snapshots are the world states recently received from the server, by tick.
+// Deltas from the server are relative to one of these, not to whatever arrived last.
+var snapshots = make(map[uint64]map[string]*shared_structs.GameObject)
+
+// maxSnapshots is how many snapshots are kept at most. The server only remembers the last 64 it sent each client,
+// so it never sends a delta from anything older.
+const maxSnapshots = 64
+
+// missingBaseline is the last baseline the server sent a delta from that wasn't in snapshots,
+// so a full snapshot is only asked for once for it
+var missingBaseline uint64
+
+// applySnapshot rebuilds the world as of newState.Tick and returns it.
+// It returns false if newState is a delta from a snapshot we no longer have.
+func applySnapshot(newState *shared_structs.WorldData) (map[string]*shared_structs.GameObject, bool) {
+	var state map[string]*shared_structs.GameObject
+	if newState.Baseline == 0 {
+		state = make(map[string]*shared_structs.GameObject, len(newState.Objects))
+	} else {
+		base, ok := snapshots[newState.Baseline]
+		if !ok {
+			return nil, false
+		}
+		state = make(map[string]*shared_structs.GameObject, len(base))
+		for key, object := range base {
+			state[key] = object
+		}
+	}
+
+	for _, delta := range newState.Objects {
+		key := delta.UUID
+		if delta.Delete {
+			delete(state, key)
+			continue
+		}
+		// objects in older snapshots are never modified, so changes go into a copy
+		object := shared_structs.GameObject{UUID: key}
+		if old, ok := state[key]; ok {
+			object = *old
+		}
+		object.ApplyDelta(delta)
+		object.Fields = 0
+		state[key] = &object
+	}
+
+	// the server never goes back to a baseline older than the one it just used,
+	// and after a full snapshot it only sends deltas from that one or newer
+	oldest := newState.Baseline
+	if newState.Baseline == 0 {
+		oldest = newState.Tick
+	}
+	for tick := range snapshots {
+		if tick < oldest {
+			delete(snapshots, tick)
+		}
+	}
+	snapshots[newState.Tick] = state
+	for len(snapshots) > maxSnapshots {
+		delete(snapshots, slices.Min(slices.Collect(maps.Keys(snapshots))))
+	}
+	return state, true
+}
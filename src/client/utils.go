@@ -10,7 +10,7 @@ import (
 
 // copy pasta from websocket example code
 // not fully clear on what this actually does for us
-func handleChannels(done chan struct{}, interrupt chan os.Signal, c WSConn) {
+func handleChannels(done chan struct{}, interrupt chan os.Signal) {
 	for {
 		select {
 		case <-done:
@@ -20,7 +20,13 @@ func handleChannels(done chan struct{}, interrupt chan os.Signal, c WSConn) {
 
 			// Cleanly close the connection by sending a close message and then
 			// waiting (with timeout) for the server to close the connection.
-			err := c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			quitting.Store(true)
+			socketMu.Lock()
+			err := errNotConnected
+			if socket != nil {
+				err = socket.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			}
+			socketMu.Unlock()
 			if err != nil {
 				slog.Debug("write close:", err)
 				return
@@ -25,8 +25,11 @@ func (n *nativeWebSocket) Close() error {
 }
 
 // DialWS dials a websocket URL for native builds and returns a WSConn.
-func DialWS(u string) (WSConn, error) {
-	c, _, err := websocket.DefaultDialer.Dial(u, nil)
+// protocols are the websocket subprotocols to ask for, in order of preference.
+func DialWS(u string, protocols []string) (WSConn, error) {
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = protocols
+	c, _, err := dialer.Dial(u, nil)
 	if err != nil {
 		return nil, err
 	}
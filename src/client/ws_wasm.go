@@ -5,27 +5,44 @@ package main
 
 import (
 	"errors"
+	"sync"
 	"syscall/js"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 // wasmConn is a minimal WebSocket wrapper using the browser WebSocket API.
 type wasmConn struct {
 	ws      js.Value
-	recv    chan []byte
+	recv    chan wasmMessage
 	closeCh chan struct{}
 	closed  bool
+	// closeOnce closes closeCh, whether we close the connection or the server does
+	closeOnce sync.Once
+	// closeErr is why the server closed the connection
+	closeErr *websocket.CloseError
+}
+
+// wasmMessage is a received message and its websocket message type
+type wasmMessage struct {
+	messageType int
+	data        []byte
 }
 
-func dialWasmWebSocket(url string) (*wasmConn, error) {
+func dialWasmWebSocket(url string, protocols []string) (*wasmConn, error) {
 	wsCtor := js.Global().Get("WebSocket")
 	if wsCtor.IsUndefined() {
 		return nil, errors.New("WebSocket not available in JS global")
 	}
-	ws := wsCtor.New(url)
+	jsProtocols := make([]any, len(protocols))
+	for i, protocol := range protocols {
+		jsProtocols[i] = protocol
+	}
+	ws := wsCtor.New(url, jsProtocols)
 	c := &wasmConn{
 		ws:      ws,
-		recv:    make(chan []byte, 256),
+		recv:    make(chan wasmMessage, 256),
 		closeCh: make(chan struct{}),
 	}
 
@@ -39,12 +56,12 @@ func dialWasmWebSocket(url string) (*wasmConn, error) {
 		data := ev.Get("data")
 		switch data.Type() {
 		case js.TypeString:
-			c.recv <- []byte(data.String())
+			c.recv <- wasmMessage{1, []byte(data.String())} // TextMessage
 		default:
 			uint8Arr := js.Global().Get("Uint8Array").New(data)
 			b := make([]byte, uint8Arr.Get("length").Int())
 			js.CopyBytesToGo(b, uint8Arr)
-			c.recv <- b
+			c.recv <- wasmMessage{2, b} // BinaryMessage
 		}
 		return nil
 	})
@@ -57,10 +74,9 @@ func dialWasmWebSocket(url string) (*wasmConn, error) {
 		return nil
 	})
 	closeCb := js.FuncOf(func(this js.Value, args []js.Value) any {
-		if !c.closed {
-			c.closed = true
-			close(c.closeCh)
-		}
+		ev := args[0]
+		c.closeErr = &websocket.CloseError{Code: ev.Get("code").Int(), Text: ev.Get("reason").String()}
+		c.markClosed()
 		return nil
 	})
 
@@ -83,9 +99,12 @@ func dialWasmWebSocket(url string) (*wasmConn, error) {
 
 func (c *wasmConn) ReadMessage() (int, []byte, error) {
 	select {
-	case b := <-c.recv:
-		return 1, b, nil
+	case msg := <-c.recv:
+		return msg.messageType, msg.data, nil
 	case <-c.closeCh:
+		if c.closeErr != nil {
+			return 0, nil, c.closeErr
+		}
 		return 0, nil, errors.New("closed")
 	}
 }
@@ -109,11 +128,18 @@ func (c *wasmConn) Close() error {
 		return nil
 	}
 	c.ws.Call("close")
-	c.closed = true
+	c.markClosed()
 	return nil
 }
 
+// markClosed stops writes and wakes up anything waiting in ReadMessage
+func (c *wasmConn) markClosed() {
+	c.closed = true
+	c.closeOnce.Do(func() { close(c.closeCh) })
+}
+
 // DialWS for wasm uses the browser WebSocket API and returns WSConn.
-func DialWS(u string) (WSConn, error) {
-	return dialWasmWebSocket(u)
+// protocols are the websocket subprotocols to ask for, in order of preference.
+func DialWS(u string, protocols []string) (WSConn, error) {
+	return dialWasmWebSocket(u, protocols)
 }
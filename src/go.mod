@@ -11,6 +11,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/hajimehoshi/ebiten/v2 v2.9.3
 	github.com/jakecoffman/cp/v2 v2.3.1
+	github.com/klauspost/compress v1.20.1
 )
 
 require (
@@ -11,4 +11,38 @@ const (
 	Tracker UserDataCode = "tracker"
 	Bomb    UserDataCode = "bomb"
 	Pickup  UserDataCode = "pickup"
+	// Shrapnel is a bullet from a bomb. It does what bullets do, but it wasn't aimed, so it doesn't count as a shot.
+	Shrapnel UserDataCode = "shrapnel"
+	// Sequence objects have no body, they exist only to run logic over time
+	Sequence UserDataCode = "sequence"
+)
+
+// Team says who is on whose side, see the combat package
+type Team string
+
+// The client always renders at this resolution and scales to fit the window,
+// so this is also how much of the world a player can see around their ship.
+const (
+	ScreenWidth  = 1920
+	ScreenHeight = 1080
+)
+
+// MetersToPixels converts physics units to screen units
+const MetersToPixels = 64
+
+// MessageType tells the server what kind of message the client sent
+type MessageType string
+
+const (
+	HelloMessage  MessageType = "hello"  // who the player is, must be the first message on a connection
+	InputMessage  MessageType = "input"  // what the player is doing
+	AckMessage    MessageType = "ack"    // the last snapshot the client received
+	ResyncMessage MessageType = "resync" // the client doesn't have the baseline of a snapshot it got, so it needs a full one
+)
+
+// Close codes the server uses when it turns a connection away during the handshake.
+// The close reason says what was wrong. Clients shouldn't reconnect after either of these.
+const (
+	CloseBadVersion = 4000 // the client speaks a different protocol version
+	CloseBadHello   = 4001 // the hello was missing, or the name or skin was no good
 )
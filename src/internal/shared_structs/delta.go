@@ -0,0 +1,119 @@
+package shared_structs
+
+import "math"
+
+// FieldMask marks which fields of a GameObject are included in a delta.
+// A zero mask means the whole object is included.
+type FieldMask uint16
+
+const (
+	FieldX FieldMask = 1 << iota
+	FieldY
+	FieldAngle
+	// FieldSprite covers the sprite name, its region on the sheet, and flips, since they change together
+	FieldSprite
+	FieldName
+	// FieldLayer covers the depth and parallax
+	FieldLayer
+)
+
+const AllFields = FieldX | FieldY | FieldAngle | FieldSprite | FieldName | FieldLayer
+
+// Diff returns the fields of g that differ from old
+func (g *GameObject) Diff(old *GameObject) FieldMask {
+	var mask FieldMask
+	if g.X != old.X {
+		mask |= FieldX
+	}
+	if g.Y != old.Y {
+		mask |= FieldY
+	}
+	// angles are only sent to 2 decimal places, so smaller changes aren't worth sending
+	if math.Round(float64(g.Angle)*100) != math.Round(float64(old.Angle)*100) {
+		mask |= FieldAngle
+	}
+	if g.Sprite != old.Sprite ||
+		g.SpriteOffsetX != old.SpriteOffsetX ||
+		g.SpriteOffsetY != old.SpriteOffsetY ||
+		g.SpriteWidth != old.SpriteWidth ||
+		g.SpriteHeight != old.SpriteHeight ||
+		g.SpriteFlipHorizontal != old.SpriteFlipHorizontal ||
+		g.SpriteFlipVertical != old.SpriteFlipVertical ||
+		g.SpriteFlipDiagonal != old.SpriteFlipDiagonal {
+		mask |= FieldSprite
+	}
+	if g.Name != old.Name {
+		mask |= FieldName
+	}
+	if g.Depth != old.Depth || g.ParallaxX != old.ParallaxX || g.ParallaxY != old.ParallaxY {
+		mask |= FieldLayer
+	}
+	return mask
+}
+
+// Delta returns a copy of g with only the fields in mask filled in
+func (g *GameObject) Delta(mask FieldMask) GameObject {
+	wire := g.Wire()
+	wire.Fields = mask
+	delta := GameObject{UUID: g.UUID, Fields: mask}
+	delta.ApplyDelta(wire)
+	return delta
+}
+
+// ApplyDelta copies the fields in delta.Fields from delta into g
+func (g *GameObject) ApplyDelta(delta GameObject) {
+	mask := delta.Fields
+	if mask == 0 {
+		mask = AllFields
+	}
+	if mask&FieldX != 0 {
+		g.X = delta.X
+	}
+	if mask&FieldY != 0 {
+		g.Y = delta.Y
+	}
+	if mask&FieldAngle != 0 {
+		g.Angle = delta.Angle
+	}
+	if mask&FieldSprite != 0 {
+		g.Sprite = delta.Sprite
+		g.SpriteOffsetX = delta.SpriteOffsetX
+		g.SpriteOffsetY = delta.SpriteOffsetY
+		g.SpriteWidth = delta.SpriteWidth
+		g.SpriteHeight = delta.SpriteHeight
+		g.SpriteFlipHorizontal = delta.SpriteFlipHorizontal
+		g.SpriteFlipVertical = delta.SpriteFlipVertical
+		g.SpriteFlipDiagonal = delta.SpriteFlipDiagonal
+	}
+	if mask&FieldName != 0 {
+		g.Name = delta.Name
+	}
+	if mask&FieldLayer != 0 {
+		g.Depth = delta.Depth
+		g.ParallaxX = delta.ParallaxX
+		g.ParallaxY = delta.ParallaxY
+	}
+}
+
+// Wire returns a copy of g with only the fields that are sent to clients,
+// so the copy doesn't keep the physics body or inbox alive
+func (g *GameObject) Wire() GameObject {
+	return GameObject{
+		X:                    g.X,
+		Y:                    g.Y,
+		Sprite:               g.Sprite,
+		SpriteOffsetX:        g.SpriteOffsetX,
+		SpriteOffsetY:        g.SpriteOffsetY,
+		SpriteWidth:          g.SpriteWidth,
+		SpriteHeight:         g.SpriteHeight,
+		SpriteFlipHorizontal: g.SpriteFlipHorizontal,
+		SpriteFlipVertical:   g.SpriteFlipVertical,
+		SpriteFlipDiagonal:   g.SpriteFlipDiagonal,
+		Angle:                g.Angle,
+		Name:                 g.Name,
+		Depth:                g.Depth,
+		ParallaxX:            g.ParallaxX,
+		ParallaxY:            g.ParallaxY,
+		UUID:                 g.UUID,
+	}
+}
@@ -0,0 +1,27 @@
+package shared_structs
+
+// Actions are what a player is doing during a tick, one bit per action.
+// The client decides which keys or buttons mean which action, the server only ever sees actions.
+type Actions uint16
+
+const (
+	ActionThrust Actions = 1 << iota
+	ActionTurnLeft
+	ActionTurnRight
+	ActionBrake
+	ActionFire
+	ActionBomb
+	ActionPortal
+)
+
+// Has returns true if every action in b is held
+func (a Actions) Has(b Actions) bool {
+	return a&b == b
+}
+
+// InputCommand is one tick of input from a client
+type InputCommand struct {
+	Seq     uint64  `json:"seq"`  // counts up by one every tick, so the server can drop duplicates and say which it has applied
+	Tick    uint64  `json:"tick"` // the server tick the client thought it was when it sampled the input
+	Actions Actions `json:"a"`
+}
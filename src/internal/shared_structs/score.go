@@ -0,0 +1,36 @@
+package shared_structs
+
+// PlayerScore is one line of the scoreboard
+type PlayerScore struct {
+	ID         string        `json:"id"`
+	Name       string        `json:"n"`
+	Team       string        `json:"tm,omitempty"` // only in modes with teams
+	Kills      int           `json:"k"`            // other players killed
+	Deaths     int           `json:"d"`
+	Assists    int           `json:"a"`
+	Turrets    int           `json:"tu"` // turrets destroyed
+	ShotsFired int           `json:"sf"`
+	ShotsHit   int           `json:"sh"`
+	Pickups    int           `json:"pu"`
+	TimeAlive  RoundedFloat2 `json:"ta"` // seconds
+}
+
+// MatchState is how the current round of the game mode is going
+type MatchState struct {
+	Mode     string        `json:"m"`
+	Map      string        `json:"mp,omitempty"`
+	Round    int           `json:"r"`
+	TimeLeft RoundedFloat2 `json:"tl,omitempty"` // seconds, 0 if there is no time limit
+	Teams    []TeamScore   `json:"ts,omitempty"`
+	// Result is set once the round is over, like who won. The next round starts NextRound seconds later,
+	// on NextMap if the map is changing.
+	Result    string        `json:"res,omitempty"`
+	NextRound RoundedFloat2 `json:"nr,omitempty"`
+	NextMap   string        `json:"nm,omitempty"`
+}
+
+// TeamScore is the score of one team, in modes with teams
+type TeamScore struct {
+	Team  string `json:"t"`
+	Score int    `json:"s"`
+}
@@ -17,43 +17,98 @@ func (r RoundedFloat2) MarshalJSON() ([]byte, error) {
 }
 
 type GameObject struct {
-	X                    int                    `json:"x"`
-	Y                    int                    `json:"y"`
-	Sprite               string                 `json:"s"`
-	SpriteOffsetX        int                    `json:"sx0"`
-	SpriteOffsetY        int                    `json:"sy0"`
-	SpriteWidth          int                    `json:"sx1"`
-	SpriteHeight         int                    `json:"sy1"`
-	SpriteFlipHorizontal bool                   `json:"sfh"`
-	SpriteFlipVertical   bool                   `json:"sfv"`
-	SpriteFlipDiagonal   bool                   `json:"sfd"`
-	Angle                RoundedFloat2          `json:"rot"`
+	X                    int                    `json:"x,omitempty"`
+	Y                    int                    `json:"y,omitempty"`
+	Sprite               string                 `json:"s,omitempty"`
+	SpriteOffsetX        int                    `json:"sx0,omitempty"`
+	SpriteOffsetY        int                    `json:"sy0,omitempty"`
+	SpriteWidth          int                    `json:"sx1,omitempty"`
+	SpriteHeight         int                    `json:"sy1,omitempty"`
+	SpriteFlipHorizontal bool                   `json:"sfh,omitempty"`
+	SpriteFlipVertical   bool                   `json:"sfv,omitempty"`
+	SpriteFlipDiagonal   bool                   `json:"sfd,omitempty"`
+	Angle                RoundedFloat2          `json:"rot,omitempty"`
+	Name                 string                 `json:"n,omitempty"`  // shown above the object, like a player's name
+	Depth                int8                   `json:"z,omitempty"`  // draw order, higher is drawn on top
+	ParallaxX            RoundedFloat2          `json:"px,omitempty"` // how much less than the world this moves on screen: 0 moves with it, 1 stays put
+	ParallaxY            RoundedFloat2          `json:"py,omitempty"`
 	UUID                 string                 `json:"id"`
-	Delete               bool                   `json:"del"`
+	Delete               bool                   `json:"del,omitempty"`
+	Fields               FieldMask              `json:"f,omitempty"` // which fields are filled in, see delta.go
 	Body                 *cp.Body               `json:"-"`
 	Shape                *cp.Shape              `json:"-"`
-	NeedsStatics         bool                   `json:"-"`
 	IsStatic             bool                   `json:"-"`
 	Identity             constants.UserDataCode `json:"-"`
-	Inbox                chan string            `json:"-"`
 	Portal               bool                   `json:"-"`
+	Damage               int                    `json:"-"` // hit points taken from a player this touches
+	Owner                string                 `json:"-"` // UUID of who gets the credit for what this does, like who fired a bullet
+	Team                 constants.Team         `json:"-"`
+}
+
+// Tick is one fixed step of the simulation.
+// Every object gets the same DeltaTime every tick, so the simulation plays out the same regardless of server load.
+type Tick struct {
+	Number    uint64
+	DeltaTime float64
 }
 
 type HasBehavior interface {
-	ApplyBehavior(deltaTime float64, spawnerPipeline chan HasBehavior)
+	ApplyBehavior(tick Tick, spawnerPipeline chan HasBehavior)
 	GetObject() *GameObject
 }
 
-type KeyStruct struct {
-	Keys []string `json:"keys"`
+// ClientMessage is everything the client sends to the server.
+// Type says which of the other fields are filled in.
+type ClientMessage struct {
+	Type constants.MessageType `json:"t"`
+	// Inputs are the most recent input commands, oldest first.
+	// A few are sent every time, so the server still gets every tick if a message goes missing.
+	Inputs []InputCommand `json:"in,omitempty"`
+	Ack    uint64         `json:"ack,omitempty"`
+	Hello  *Hello         `json:"hello,omitempty"`
+}
+
+// Hello is the first message on every connection. The player isn't spawned until the server accepts it.
+type Hello struct {
+	Version int    `json:"v"` // wire.Version, so clients and servers that don't match find out straight away
+	Name    string `json:"name"`
+	Skin    string `json:"skin"` // one of ship.Skins
+}
+
+// ShipState is the exact physics state of the player's own ship.
+// The client predicts its own ship, and corrects the prediction with this.
+type ShipState struct {
+	Seq             uint64  `json:"seq"` // the last input applied
+	X               float64 `json:"x"`
+	Y               float64 `json:"y"`
+	VX              float64 `json:"vx"`
+	VY              float64 `json:"vy"`
+	Angle           float64 `json:"a"`
+	AngularVelocity float64 `json:"av"`
 }
 
 type GameData struct {
-	Portal     bool   `json:"portal"`
-	PlayerUUID string `json:"pud"`
+	Portal     bool      `json:"portal"`
+	PlayerUUID string    `json:"pud"`
+	TickRate   int       `json:"tr"` // ticks per second, so the client knows how far apart snapshots are
+	Ship       ShipState `json:"ship"`
+	Health     int       `json:"hp"`
+	MaxHealth  int       `json:"mhp"`
+	// RespawnIn is how many seconds until the player respawns, while they are dead
+	RespawnIn float64 `json:"rsp,omitempty"`
+	// Session is the token to reconnect with. It is only sent with full snapshots.
+	Session string `json:"sess,omitempty"`
 }
 
+// WorldData is a snapshot of the world as of Tick.
+// If Baseline is 0 the snapshot is complete, otherwise Objects only holds what changed since the Baseline tick,
+// which is the last snapshot the client acknowledged.
 type WorldData struct {
+	Tick     uint64       `json:"t"`
+	Baseline uint64       `json:"b,omitempty"`
 	Objects  []GameObject `json:"objects"`
 	GameData GameData     `json:"gd"`
+	// Scoreboard and Match are pushed every so often, and with every full snapshot. They are empty the rest of the time.
+	Scoreboard []PlayerScore `json:"sb,omitempty"`
+	Match      *MatchState   `json:"mt,omitempty"`
 }
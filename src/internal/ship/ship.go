@@ -0,0 +1,76 @@
+package ship
+
+// The ship flight model lives here so that the server and the client's prediction move ships exactly the same way.
+// If this changes, both have to be rebuilt, or the client's predictions will keep getting corrected.
+
+import (
+	"Geomyidae/internal/shared_structs"
+	"math"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// these could be multiplied by delta time
+const thrust = 2
+const maxSpeed = 25.0
+const turn = 2
+
+// Controls are the ship controls held during a tick
+type Controls struct {
+	Thrust bool
+	Left   bool
+	Right  bool
+	Brake  bool
+}
+
+// ControlsFor picks the ship controls out of a tick's actions
+func ControlsFor(actions shared_structs.Actions) Controls {
+	return Controls{
+		Thrust: actions.Has(shared_structs.ActionThrust),
+		Left:   actions.Has(shared_structs.ActionTurnLeft),
+		Right:  actions.Has(shared_structs.ActionTurnRight),
+		Brake:  actions.Has(shared_structs.ActionBrake),
+	}
+}
+
+// NewBody creates a ship body and its shape, but does not add them to a space
+func NewBody() (*cp.Body, *cp.Shape) {
+	body := cp.NewBody(1, 1)
+	shape := cp.NewBox(body, 1, 1, 0)
+	shape.SetElasticity(0.25)
+	shape.SetDensity(0.5)
+	shape.SetFriction(1.0)
+	body.AddShape(shape)
+	return body, shape
+}
+
+// Fly applies one tick of controls to a ship body. The body still has to be stepped by its space afterward.
+func Fly(body *cp.Body, controls Controls, deltaTime float64) {
+	tr := thrust * deltaTime
+	tn := turn * deltaTime
+	x, y := body.Velocity().X, body.Velocity().Y
+	if math.Abs(x)+math.Abs(y) > maxSpeed {
+		body.SetVelocityVector(body.Velocity().Mult(0.95))
+	}
+	if controls.Left {
+		rot := body.Angle()
+		body.SetAngle(rot - tn)
+		body.SetAngularVelocity(0)
+	}
+	if controls.Right {
+		rot := body.Angle()
+		body.SetAngle(rot + tn)
+		body.SetAngularVelocity(0)
+	}
+	if controls.Brake {
+		body.SetVelocityVector(body.Velocity().Mult(0.95))
+		body.SetAngularVelocity(body.AngularVelocity() * 0.75)
+	}
+	// thrust goes last so that it pushes in the direction the ship just turned to
+	if controls.Thrust {
+		body.ApplyImpulseAtLocalPoint(cp.Vector{
+			X: -math.Sin(tr),
+			Y: -math.Cos(-tr),
+		}, cp.Vector{X: 0, Y: 0})
+	}
+}
@@ -0,0 +1,24 @@
+package ship
+
+// Skin is where a ship's picture is on the sprite sheet
+type Skin struct {
+	Sprite string
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+// DefaultSkin is the ship players get if they don't pick one
+const DefaultSkin = "classic"
+
+// Skins are the ships a player can pick from when they join, by name
+var Skins = map[string]Skin{
+	"classic": {Sprite: "spaceShooterRedux", X: 325, Y: 0, Width: 98, Height: 75},
+	"arrow":   {Sprite: "spaceShooterRedux", X: 325, Y: 739, Width: 98, Height: 75},
+	"viper":   {Sprite: "spaceShooterRedux", X: 346, Y: 75, Width: 98, Height: 75},
+	"blue":    {Sprite: "spaceShooterRedux", X: 211, Y: 941, Width: 99, Height: 75},
+	"green":   {Sprite: "spaceShooterRedux", X: 237, Y: 377, Width: 99, Height: 75},
+	"orange":  {Sprite: "spaceShooterRedux", X: 247, Y: 84, Width: 99, Height: 75},
+	"red":     {Sprite: "spaceShooterRedux", X: 224, Y: 832, Width: 99, Height: 75},
+}
@@ -0,0 +1,195 @@
+package tiled
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Tiles in a tileset can have a class, custom properties and collision shapes drawn in Tiled's collision editor.
+// They are read once when the map is loaded, and handed out with every Tile that uses them.
+
+// tilesetTile is a <tile> in a tileset, which is only there for tiles that have something set on them
+type tilesetTile struct {
+	ID          uint32     `xml:"id,attr"`
+	Type        string     `xml:"type,attr"`  // Tiled 1.8 and 1.10 and later
+	Class       string     `xml:"class,attr"` // Tiled 1.9
+	Properties  Properties `xml:"properties"`
+	Objectgroup struct {
+		Object []xmlObject `xml:"object"`
+	} `xml:"objectgroup"`
+}
+
+// ShapeKind is what kind of collision shape a Shape is
+type ShapeKind int
+
+const (
+	// ShapePolygon is a closed shape: a rectangle or polygon in Tiled, or an ellipse that isn't a circle
+	ShapePolygon ShapeKind = iota
+	// ShapePolyline is a line along Points, only its edges are solid
+	ShapePolyline
+	// ShapeCircle is a circle of Radius around Center
+	ShapeCircle
+)
+
+// Point is a position in tiles
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Shape is one collision shape of a tile, in tiles from the tile's top left corner, so the tile goes from 0,0 to 1,1
+type Shape struct {
+	Kind   ShapeKind
+	Points []Point // for polygons and polylines
+	Center Point   // for circles
+	Radius float64 // for circles
+}
+
+// ellipseSegments is how many sides a polygon standing in for an ellipse has
+const ellipseSegments = 16
+
+// tileInfo is what the tileset says about one tile
+type tileInfo struct {
+	class      string
+	properties map[string]string
+	collision  []Shape
+}
+
+// loadTileInfo reads the class, properties and collision shapes of every tile that has them, by global tile ID
+func (m *Map) loadTileInfo() (map[uint32]tileInfo, error) {
+	infos := make(map[uint32]tileInfo)
+	for _, tileset := range m.Tileset {
+		for _, t := range tileset.Tiles {
+			info := tileInfo{class: t.Class, properties: t.Properties.Map()}
+			if info.class == "" {
+				info.class = t.Type
+			}
+			for _, o := range t.Objectgroup.Object {
+				shape, ok, err := collisionShape(o, float64(tileset.Tilewidth), float64(tileset.Tileheight))
+				if err != nil {
+					return nil, &Error{File: m.file, Err: fmt.Errorf("tileset %v, tile %d, collision object %d: %w", tileset.Name, t.ID, o.ID, err)}
+				}
+				if ok {
+					info.collision = append(info.collision, shape)
+				}
+			}
+			if len(info.collision) == 1 && info.collision[0].wholeTile() {
+				// the same as not drawing any
+				info.collision = nil
+			}
+			infos[uint32(tileset.Firstgid)+t.ID] = info
+		}
+	}
+	return infos, nil
+}
+
+// collisionShape converts an object from the collision editor to tiles. Points and empty rectangles aren't shapes, ok is
+// false for them.
+func collisionShape(o xmlObject, tileWidth, tileHeight float64) (shape Shape, ok bool, err error) {
+	var points []Point
+	switch {
+	case o.Polygon != nil || o.Polyline != nil:
+		shape.Kind = ShapePolygon
+		list := o.Polygon
+		if list == nil {
+			shape.Kind = ShapePolyline
+			list = o.Polyline
+		}
+		points, err = parsePoints(list.Points)
+		if err != nil {
+			return shape, false, err
+		}
+		if len(points) < 2 || shape.Kind == ShapePolygon && len(points) < 3 {
+			return shape, false, fmt.Errorf("%d points aren't enough", len(points))
+		}
+	case o.Width <= 0 || o.Height <= 0:
+		return shape, false, nil
+	case o.Ellipse != nil && o.Width == o.Height && o.Rotation == 0:
+		return Shape{
+			Kind:   ShapeCircle,
+			Center: Point{X: (o.X + o.Width/2) / tileWidth, Y: (o.Y + o.Height/2) / tileHeight},
+			Radius: o.Width / 2 / tileWidth,
+		}, true, nil
+	case o.Ellipse != nil:
+		shape.Kind = ShapePolygon
+		for i := range ellipseSegments {
+			angle := 2 * math.Pi * float64(i) / ellipseSegments
+			points = append(points, Point{X: o.Width / 2 * (1 + math.Cos(angle)), Y: o.Height / 2 * (1 + math.Sin(angle))})
+		}
+	default:
+		shape.Kind = ShapePolygon
+		points = []Point{{0, 0}, {o.Width, 0}, {o.Width, o.Height}, {0, o.Height}}
+	}
+
+	// points are relative to the object's position, and turn around it clockwise
+	sin, cos := math.Sincos(o.Rotation * math.Pi / 180)
+	for _, p := range points {
+		shape.Points = append(shape.Points, Point{
+			X: (o.X + p.X*cos - p.Y*sin) / tileWidth,
+			Y: (o.Y + p.X*sin + p.Y*cos) / tileHeight,
+		})
+	}
+	return shape, true, nil
+}
+
+// parsePoints reads Tiled's "x,y x,y ..." point lists
+func parsePoints(list string) ([]Point, error) {
+	var points []Point
+	for _, pair := range strings.Fields(list) {
+		xs, ys, found := strings.Cut(pair, ",")
+		if !found {
+			return nil, fmt.Errorf("point %q isn't x,y", pair)
+		}
+		x, errX := strconv.ParseFloat(xs, 64)
+		y, errY := strconv.ParseFloat(ys, 64)
+		if err := errors.Join(errX, errY); err != nil {
+			return nil, fmt.Errorf("point %q: %w", pair, err)
+		}
+		points = append(points, Point{X: x, Y: y})
+	}
+	return points, nil
+}
+
+// wholeTile reports whether the shape is a rectangle covering exactly the whole tile
+func (s Shape) wholeTile() bool {
+	if s.Kind != ShapePolygon || len(s.Points) != 4 {
+		return false
+	}
+	const epsilon = 1e-6
+	for _, corner := range []Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}} {
+		found := false
+		for _, p := range s.Points {
+			if math.Abs(p.X-corner.X) < epsilon && math.Abs(p.Y-corner.Y) < epsilon {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// flip turns the shape the same way the tile is turned. Tiled flips diagonally first, then horizontally, then vertically.
+func (s Shape) flip(horizontal, vertical, diagonal bool) Shape {
+	turn := func(p Point) Point {
+		if diagonal {
+			p.X, p.Y = p.Y, p.X
+		}
+		if horizontal {
+			p.X = 1 - p.X
+		}
+		if vertical {
+			p.Y = 1 - p.Y
+		}
+		return p
+	}
+	flipped := Shape{Kind: s.Kind, Center: turn(s.Center), Radius: s.Radius}
+	for _, p := range s.Points {
+		flipped.Points = append(flipped.Points, turn(p))
+	}
+	return flipped
+}
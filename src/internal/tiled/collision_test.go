@@ -0,0 +1,160 @@
+package tiled
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// near reports whether a and b are the same points, give or take rounding
+func near(a, b []Point) bool {
+	return slices.EqualFunc(a, b, func(p, q Point) bool {
+		return math.Abs(p.X-q.X) < 1e-9 && math.Abs(p.Y-q.Y) < 1e-9
+	})
+}
+
+func TestCollisionShape(t *testing.T) {
+	tests := []struct {
+		name    string
+		object  xmlObject
+		want    Shape
+		ok      bool
+		wantErr bool
+	}{
+		{
+			name:   "rectangle",
+			object: xmlObject{X: 0, Y: 32, Width: 64, Height: 32},
+			want:   Shape{Kind: ShapePolygon, Points: []Point{{0, 0.5}, {1, 0.5}, {1, 1}, {0, 1}}},
+			ok:     true,
+		},
+		{
+			name:   "rotated rectangle",
+			object: xmlObject{X: 64, Y: 0, Width: 64, Height: 32, Rotation: 90},
+			want:   Shape{Kind: ShapePolygon, Points: []Point{{1, 0}, {1, 1}, {0.5, 1}, {0.5, 0}}},
+			ok:     true,
+		},
+		{
+			name:   "polygon",
+			object: xmlObject{X: 0, Y: 64, Polygon: &xmlPoints{Points: "0,0 64,-64 64,0"}},
+			want:   Shape{Kind: ShapePolygon, Points: []Point{{0, 1}, {1, 0}, {1, 1}}},
+			ok:     true,
+		},
+		{
+			name:   "polyline",
+			object: xmlObject{X: 0, Y: 16, Polyline: &xmlPoints{Points: "0,0 64,0"}},
+			want:   Shape{Kind: ShapePolyline, Points: []Point{{0, 0.25}, {1, 0.25}}},
+			ok:     true,
+		},
+		{
+			name:   "circle",
+			object: xmlObject{X: 16, Y: 16, Width: 32, Height: 32, Ellipse: &struct{}{}},
+			want:   Shape{Kind: ShapeCircle, Center: Point{0.5, 0.5}, Radius: 0.25},
+			ok:     true,
+		},
+		{
+			name:   "point",
+			object: xmlObject{X: 16, Y: 16},
+		},
+		{
+			name:    "bad point",
+			object:  xmlObject{Polygon: &xmlPoints{Points: "0,0 64;0 0,64"}},
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			object:  xmlObject{Polygon: &xmlPoints{Points: "0,0 64,x 0,64"}},
+			wantErr: true,
+		},
+		{
+			name:    "two point polygon",
+			object:  xmlObject{Polygon: &xmlPoints{Points: "0,0 64,64"}},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok, err := collisionShape(test.object, 64, 64)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("the error is %v, want an error: %v", err, test.wantErr)
+			}
+			if ok != test.ok {
+				t.Fatalf("ok is %v, want %v", ok, test.ok)
+			}
+			if !ok {
+				return
+			}
+			if got.Kind != test.want.Kind || !near(got.Points, test.want.Points) || got.Center != test.want.Center || got.Radius != test.want.Radius {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEllipse(t *testing.T) {
+	shape, ok, err := collisionShape(xmlObject{Width: 64, Height: 32, Ellipse: &struct{}{}}, 64, 64)
+	if err != nil || !ok {
+		t.Fatalf("ok is %v, the error is %v", ok, err)
+	}
+	if shape.Kind != ShapePolygon || len(shape.Points) != ellipseSegments {
+		t.Fatalf("got a %v with %d points, want a polygon with %d", shape.Kind, len(shape.Points), ellipseSegments)
+	}
+	for _, p := range shape.Points {
+		// (x - 0.5)² / 0.5² + (y - 0.25)² / 0.25² = 1
+		if d := math.Pow((p.X-0.5)/0.5, 2) + math.Pow((p.Y-0.25)/0.25, 2); math.Abs(d-1) > 1e-9 {
+			t.Errorf("%v isn't on the ellipse", p)
+		}
+	}
+}
+
+func TestFlip(t *testing.T) {
+	// a slope rising to the right, solid underneath
+	slope := Shape{Kind: ShapePolygon, Points: []Point{{0, 1}, {1, 0}, {1, 1}}}
+	tests := []struct {
+		name                           string
+		horizontal, vertical, diagonal bool
+		want                           []Point
+	}{
+		{name: "not at all", want: []Point{{0, 1}, {1, 0}, {1, 1}}},
+		{name: "horizontally", horizontal: true, want: []Point{{1, 1}, {0, 0}, {0, 1}}},
+		{name: "vertically", vertical: true, want: []Point{{0, 0}, {1, 1}, {1, 0}}},
+		{name: "both ways", horizontal: true, vertical: true, want: []Point{{1, 0}, {0, 1}, {0, 0}}},
+		{name: "diagonally", diagonal: true, want: []Point{{1, 0}, {0, 1}, {1, 1}}},
+		// Tiled rotates a tile 90° clockwise by flipping it diagonally, then horizontally
+		{name: "rotated 90°", diagonal: true, horizontal: true, want: []Point{{0, 0}, {1, 1}, {0, 1}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := slope.flip(test.horizontal, test.vertical, test.diagonal)
+			if got.Kind != slope.Kind || !near(got.Points, test.want) {
+				t.Errorf("got %v, want %v", got.Points, test.want)
+			}
+		})
+	}
+
+	circle := Shape{Kind: ShapeCircle, Center: Point{0.25, 0.75}, Radius: 0.25}
+	got := circle.flip(true, false, true)
+	if want := (Point{0.25, 0.25}); got.Center != want || got.Radius != circle.Radius {
+		t.Errorf("the circle went to %v with radius %v, want %v with radius %v", got.Center, got.Radius, want, circle.Radius)
+	}
+}
+
+// TestTileCollision loads tiles with collision shapes, to check they come out of TileData flipped with the tile
+func TestTileCollision(t *testing.T) {
+	tiles, err := load(t, "shapes.tmx").TileData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tiles) != 3 {
+		t.Fatalf("got %d tiles, want 3", len(tiles))
+	}
+	slope, flipped, box := tiles[0], tiles[1], tiles[2]
+	if slope.Class != "slope" || len(slope.Collision) != 1 || !near(slope.Collision[0].Points, []Point{{0, 0}, {1, 1}, {0, 1}}) {
+		t.Errorf("the slope is a %q with %+v", slope.Class, slope.Collision)
+	}
+	if !flipped.SpriteFlipHorizontal || len(flipped.Collision) != 1 || !near(flipped.Collision[0].Points, []Point{{1, 0}, {0, 1}, {1, 1}}) {
+		t.Errorf("the flipped slope has %+v", flipped.Collision)
+	}
+	if len(box.Collision) != 0 {
+		t.Errorf("a box over the whole tile should be the same as no shape, got %+v", box.Collision)
+	}
+}
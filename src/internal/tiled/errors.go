@@ -0,0 +1,33 @@
+package tiled
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// ErrUnsupported is wrapped by errors about things Tiled can do that the loader can't, like isometric maps
+var ErrUnsupported = errors.New("not supported")
+
+// Error is something wrong with a map, and where in the map it is
+type Error struct {
+	File  string       // the map or tileset file
+	Layer string       // the layer it is on, if it is on one
+	Chunk *image.Point // where the chunk starts in tiles, if it is in one chunk of an infinite map
+	Err   error
+}
+
+func (e *Error) Error() string {
+	where := e.File
+	if e.Layer != "" {
+		where += fmt.Sprintf(", layer %q", e.Layer)
+	}
+	if e.Chunk != nil {
+		where += fmt.Sprintf(", chunk at %d,%d", e.Chunk.X, e.Chunk.Y)
+	}
+	return where + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
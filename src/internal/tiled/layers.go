@@ -0,0 +1,162 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Role is what a tile layer is for, set with a role property on the layer or a group it is in
+type Role string
+
+const (
+	// RoleCollision tiles are solid. Layers are collision layers unless they say otherwise.
+	RoleCollision Role = "collision"
+	// RoleDecoration tiles are only drawn, nothing bumps into them
+	RoleDecoration Role = "decoration"
+	// RoleParallax tiles are drawn scrolling at their own speed, set with the layer's parallax factor in Tiled.
+	// Layers with a parallax factor are parallax layers unless they say otherwise.
+	RoleParallax Role = "parallax"
+)
+
+// tileLayer is a tile layer with the settings of the groups it is in applied
+type tileLayer struct {
+	Layer
+	role                 Role
+	offsetX, offsetY     float64 // pixels
+	parallaxX, parallaxY float64
+}
+
+// tileLayers returns the visible tile layers, in the order they are drawn
+func (m *Map) tileLayers() ([]tileLayer, error) {
+	var layers []tileLayer
+	var walk func(layer Layer, parent tileLayer)
+	walk = func(layer Layer, parent tileLayer) {
+		if layer.Visible == "0" {
+			return
+		}
+		current := tileLayer{
+			Layer:     layer,
+			role:      parent.role,
+			offsetX:   parent.offsetX + layer.Offsetx,
+			offsetY:   parent.offsetY + layer.Offsety,
+			parallaxX: parent.parallaxX,
+			parallaxY: parent.parallaxY,
+		}
+		if role, ok := layer.Properties.Map()["role"]; ok {
+			current.role = Role(role)
+		}
+		if layer.Parallaxx != nil {
+			current.parallaxX *= *layer.Parallaxx
+		}
+		if layer.Parallaxy != nil {
+			current.parallaxY *= *layer.Parallaxy
+		}
+
+		switch layer.XMLName.Local {
+		case "group":
+			for _, child := range layer.Layers {
+				walk(child, current)
+			}
+		case "layer":
+			if current.role == "" {
+				current.role = RoleCollision
+				if current.parallaxX != 1 || current.parallaxY != 1 {
+					current.role = RoleParallax
+				}
+			}
+			layers = append(layers, current)
+		}
+	}
+	for _, layer := range m.Layers {
+		walk(layer, tileLayer{parallaxX: 1, parallaxY: 1})
+	}
+	for _, layer := range layers {
+		if layer.role != RoleCollision && layer.role != RoleDecoration && layer.role != RoleParallax {
+			return nil, &Error{File: m.file, Layer: layer.Name, Err: fmt.Errorf("role is %q, it can be %q, %q or %q", layer.role, RoleCollision, RoleDecoration, RoleParallax)}
+		}
+	}
+	return layers, nil
+}
+
+// decodeChunk reads the global tile IDs out of a chunk, in rows from the top left
+func decodeChunk(chunk Chunk, encoding string, compression string) ([]uint32, error) {
+	switch encoding {
+	case "":
+		// the deprecated XML encoding, with a <tile> for every tile
+		gids := make([]uint32, len(chunk.Tile))
+		for i, tile := range chunk.Tile {
+			gids[i] = tile.Gid
+		}
+		return gids, nil
+	case "csv":
+		var gids []uint32
+		for _, field := range strings.Split(chunk.Text, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			gid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			gids = append(gids, uint32(gid))
+		}
+		return gids, nil
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(chunk.Text))
+		if err != nil {
+			return nil, err
+		}
+		data, err = decompress(data, compression)
+		if err != nil {
+			return nil, err
+		}
+		if len(data)%4 != 0 {
+			return nil, errors.New("tile data isn't a whole number of tiles")
+		}
+		// The data is an array of bytes, which should be interpreted as an array of unsigned 32-bit integers using little-endian byte ordering.
+		gids := make([]uint32, 0, len(data)/4)
+		for tileIndex := 0; tileIndex < len(data); tileIndex += 4 {
+			gids = append(gids, binary.LittleEndian.Uint32(data[tileIndex:tileIndex+4]))
+		}
+		return gids, nil
+	default:
+		return nil, fmt.Errorf("unknown tile layer encoding %q", encoding)
+	}
+}
+
+func decompress(data []byte, compression string) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+	switch compression {
+	case "":
+		return data, nil
+	case "gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(data))
+	case "zlib":
+		reader, err = zlib.NewReader(bytes.NewReader(data))
+	case "zstd":
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(bytes.NewReader(data))
+		if err == nil {
+			reader = decoder.IOReadCloser()
+		}
+	default:
+		return nil, fmt.Errorf("unknown tile layer compression %q", compression)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
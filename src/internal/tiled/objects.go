@@ -0,0 +1,101 @@
+package tiled
+
+import "fmt"
+
+// Object is something placed on an object layer in Tiled, like a spawn point or a trigger.
+// Positions and sizes are in tiles, which is meters on the server.
+type Object struct {
+	ID    int
+	Name  string
+	Class string // the object's class, or its type in maps from before Tiled 1.9
+	Layer string // the name of the object layer it is on
+	// X and Y are the middle of the object
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+	// Tile objects have the sprite of their tile, Sprite is empty for everything else
+	Sprite               string
+	SpriteOffsetX        int
+	SpriteOffsetY        int
+	SpriteWidth          int
+	SpriteHeight         int
+	SpriteFlipHorizontal bool
+	SpriteFlipVertical   bool
+	SpriteFlipDiagonal   bool
+	Properties           map[string]string
+}
+
+// Objects returns everything on the map's visible object layers
+func (m *Map) Objects() ([]Object, error) {
+	var objects []Object
+	var walk func(layer Layer, offsetX, offsetY float64) error
+	walk = func(layer Layer, offsetX, offsetY float64) error {
+		if layer.Visible == "0" {
+			return nil
+		}
+		offsetX += layer.Offsetx
+		offsetY += layer.Offsety
+		switch layer.XMLName.Local {
+		case "group":
+			for _, child := range layer.Layers {
+				err := walk(child, offsetX, offsetY)
+				if err != nil {
+					return err
+				}
+			}
+		case "objectgroup":
+			for _, o := range layer.Object {
+				object, err := m.object(layer.Name, o, offsetX, offsetY)
+				if err != nil {
+					return &Error{File: m.file, Layer: layer.Name, Err: fmt.Errorf("object %d: %w", o.ID, err)}
+				}
+				objects = append(objects, object)
+			}
+		}
+		return nil
+	}
+	for _, layer := range m.Layers {
+		err := walk(layer, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// object converts o to tiles, and looks up its sprite if it is a tile object
+func (m *Map) object(layer string, o xmlObject, offsetX, offsetY float64) (Object, error) {
+	class := o.Class
+	if class == "" {
+		class = o.Type
+	}
+	// Tiled positions are in pixels, from the top left corner of the object
+	object := Object{
+		ID:         o.ID,
+		Name:       o.Name,
+		Class:      class,
+		Layer:      layer,
+		X:          (o.X + offsetX + o.Width/2) / float64(m.Tilewidth),
+		Y:          (o.Y + offsetY + o.Height/2) / float64(m.Tileheight),
+		Width:      o.Width / float64(m.Tilewidth),
+		Height:     o.Height / float64(m.Tileheight),
+		Properties: o.Properties.Map(),
+	}
+	if gid := o.Gid; gid != 0 {
+		// except tile objects, which are positioned from their bottom left corner
+		object.Y -= o.Height / float64(m.Tileheight)
+		object.SpriteFlipHorizontal = gid&FLIPPED_HORIZONTALLY_FLAG != 0
+		object.SpriteFlipVertical = gid&FLIPPED_VERTICALLY_FLAG != 0
+		object.SpriteFlipDiagonal = gid&FLIPPED_DIAGONALLY_FLAG != 0
+		gid &^= 0xF0000000
+		tileset, err := m.tileset(gid)
+		if err != nil {
+			return object, err
+		}
+		object.Sprite, object.SpriteOffsetX, object.SpriteOffsetY = tileSprite(tileset, gid)
+		object.SpriteWidth = tileset.Tilewidth
+		object.SpriteHeight = tileset.Tileheight
+	}
+	return object, nil
+}
@@ -0,0 +1,9 @@
+<?xml version="1.0" encoding="UTF-8"?>
+<tileset version="1.10" tiledversion="1.11.2" name="tiles" tilewidth="64" tileheight="64" tilecount="4" columns="2">
+ <image source="tiles.png" width="128" height="128"/>
+ <tile id="1">
+  <properties>
+   <property name="friction" value="0.1"/>
+  </properties>
+ </tile>
+</tileset>
@@ -3,15 +3,17 @@ package tiled
 // This will import a Tiled native .tmx file and extract tile data from it.
 // (At least, the bytes from said file.)
 // There is no need to "export" from Tiled to JSON or other formats.
-// Use base64 encoded and uncompressed data in Tiled, with infinite map enabled.
+// Maps can be finite or infinite, with any number of tile layers, stored as CSV, XML or base64
+// (uncompressed, gzip, zlib or zstd). Tilesets can be embedded in the map or saved as separate .tsx files.
+// Tiles can have properties and collision shapes set in the tileset, see collision.go.
 
 import (
-	"encoding/base64"
-	"encoding/binary"
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"log"
-	"strings"
+	"image"
+	"io/fs"
+	"path"
 )
 
 // https://blog.kowalczyk.info/tools/xmltogo/
@@ -37,43 +39,123 @@ type Map struct {
 			Format string `xml:"format,attr"`
 		} `xml:"export"`
 	} `xml:"editorsettings"`
-	Tileset []struct {
-		Text       string `xml:",chardata"`
-		Firstgid   int    `xml:"firstgid,attr"`
-		Name       string `xml:"name,attr"`
-		Tilewidth  int    `xml:"tilewidth,attr"`
-		Tileheight int    `xml:"tileheight,attr"`
-		Tilecount  int    `xml:"tilecount,attr"`
-		Columns    int    `xml:"columns,attr"`
-		Image      struct {
-			Text   string `xml:",chardata"`
-			Source string `xml:"source,attr"`
-			Width  string `xml:"width,attr"`
-			Height string `xml:"height,attr"`
-		} `xml:"image"`
-	} `xml:"tileset"`
-	Layer struct {
+	Properties Properties `xml:"properties"`
+	Tileset    []Tileset  `xml:"tileset"`
+	// Layers are the tile layers, object layers and groups, in the order Tiled draws them
+	Layers []Layer `xml:",any"`
+
+	file  string              // the name it was loaded from, for errors
+	tiles map[uint32]tileInfo // by global tile ID, see loadTileInfo
+}
+
+// Tileset is either embedded in the map, or in a .tsx file named by Source
+type Tileset struct {
+	Text       string `xml:",chardata"`
+	Firstgid   int    `xml:"firstgid,attr"`
+	Source     string `xml:"source,attr"`
+	Name       string `xml:"name,attr"`
+	Tilewidth  int    `xml:"tilewidth,attr"`
+	Tileheight int    `xml:"tileheight,attr"`
+	Tilecount  int    `xml:"tilecount,attr"`
+	Columns    int    `xml:"columns,attr"`
+	Image      struct {
 		Text   string `xml:",chardata"`
-		ID     string `xml:"id,attr"`
-		Name   string `xml:"name,attr"`
-		Width  int    `xml:"width,attr"`
-		Height int    `xml:"height,attr"`
-		Data   struct {
-			Text        string `xml:",chardata"`
-			Encoding    string `xml:"encoding,attr"`
-			Compression string `xml:"compression,attr"`
-			Chunk       []struct {
-				Text   string `xml:",chardata"`
-				X      int    `xml:"x,attr"`
-				Y      int    `xml:"y,attr"`
-				Width  int    `xml:"width,attr"`
-				Height int    `xml:"height,attr"`
-			} `xml:"chunk"`
-		} `xml:"data"`
-	} `xml:"layer"`
+		Source string `xml:"source,attr"`
+		Width  string `xml:"width,attr"`
+		Height string `xml:"height,attr"`
+	} `xml:"image"`
+	// Tiles are the tiles that have properties or collision shapes set in the tileset editor
+	Tiles []tilesetTile `xml:"tile"`
+}
+
+// Layer is a <layer>, <objectgroup>, <group> or <imagelayer>, which XMLName tells apart.
+// Groups hold more layers, and any of their settings apply to everything in them.
+type Layer struct {
+	XMLName    xml.Name
+	Text       string     `xml:",chardata"`
+	ID         string     `xml:"id,attr"`
+	Name       string     `xml:"name,attr"`
+	Width      int        `xml:"width,attr"`
+	Height     int        `xml:"height,attr"`
+	Visible    string     `xml:"visible,attr"` // "0" if the layer is hidden
+	Offsetx    float64    `xml:"offsetx,attr"`
+	Offsety    float64    `xml:"offsety,attr"`
+	Parallaxx  *float64   `xml:"parallaxx,attr"`
+	Parallaxy  *float64   `xml:"parallaxy,attr"`
+	Properties Properties `xml:"properties"`
+	Data       struct {
+		Text        string    `xml:",chardata"`
+		Encoding    string    `xml:"encoding,attr"`
+		Compression string    `xml:"compression,attr"`
+		Tile        []xmlTile `xml:"tile"`
+		Chunk       []Chunk   `xml:"chunk"`
+	} `xml:"data"`
+	Object []xmlObject `xml:"object"`
+	Layers []Layer     `xml:",any"` // only groups have these
+}
+
+// xmlObject is an object on an object layer, see Object for the parsed version
+type xmlObject struct {
+	Text       string     `xml:",chardata"`
+	ID         int        `xml:"id,attr"`
+	Name       string     `xml:"name,attr"`
+	Type       string     `xml:"type,attr"`  // before Tiled 1.9
+	Class      string     `xml:"class,attr"` // Tiled 1.9 and later
+	Gid        uint32     `xml:"gid,attr"`
+	X          float64    `xml:"x,attr"`
+	Y          float64    `xml:"y,attr"`
+	Width      float64    `xml:"width,attr"`
+	Height     float64    `xml:"height,attr"`
+	Rotation   float64    `xml:"rotation,attr"` // degrees clockwise
+	Properties Properties `xml:"properties"`
+	// Polygon, Polyline and Ellipse say what shape the object is, it is a rectangle if none of them are set
+	Polygon  *xmlPoints `xml:"polygon"`
+	Polyline *xmlPoints `xml:"polyline"`
+	Ellipse  *struct{}  `xml:"ellipse"`
+}
+
+// xmlPoints are the points of a polygon or polyline, as "x,y x,y ..." relative to the object's position
+type xmlPoints struct {
+	Points string `xml:"points,attr"`
+}
+
+// Chunk is one piece of an infinite map's layer
+type Chunk struct {
+	Text   string    `xml:",chardata"`
+	X      int       `xml:"x,attr"`
+	Y      int       `xml:"y,attr"`
+	Width  int       `xml:"width,attr"`
+	Height int       `xml:"height,attr"`
+	Tile   []xmlTile `xml:"tile"`
+}
+
+// xmlTile is one tile of a layer saved with the (deprecated) XML encoding
+type xmlTile struct {
+	Gid uint32 `xml:"gid,attr"`
+}
+
+// Properties are the custom properties on a map, layer or object
+type Properties struct {
+	Text     string `xml:",chardata"`
+	Property []struct {
+		Text  string `xml:",chardata"`
+		Name  string `xml:"name,attr"`
+		Type  string `xml:"type,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"property"`
 }
 
-type tileDatum struct {
+// Map returns the properties by name
+func (p Properties) Map() map[string]string {
+	properties := make(map[string]string)
+	for _, property := range p.Property {
+		properties[property.Name] = property.Value
+	}
+	return properties
+}
+
+// Tile is one tile on a tile layer
+type Tile struct {
 	ID                   uint32
 	Row                  int
 	Col                  int
@@ -85,10 +167,25 @@ type tileDatum struct {
 	SpriteFlipHorizontal bool   `json:"sprite_flip_horizontal"`
 	SpriteFlipVertical   bool   `json:"sprite_flip_vertical"`
 	SpriteFlipDiagonal   bool   `json:"sprite_flip_diagonal"`
+	// Layer is the name of the layer the tile is on, and Role what the layer is for
+	Layer string
+	Role  Role
+	// Depth is the draw order: tiles on layers under the first collision layer are negative, over the last one positive
+	Depth int8
+	// OffsetX and OffsetY are the layer's offset in tiles
+	OffsetX float64
+	OffsetY float64
+	// ParallaxX and ParallaxY are the layer's parallax factor in Tiled: how far it scrolls compared to the rest of the map
+	ParallaxX float64
+	ParallaxY float64
+	// Class and Properties are set on the tile in the tileset editor
+	Class      string
+	Properties map[string]string
+	// Collision is the tile's collision shapes from the tileset's collision editor, already flipped the way the tile is.
+	// If it is empty, the whole tile is solid.
+	Collision []Shape
 }
 
-var tileData []tileDatum
-
 // Bits on the far end of the 32-bit global tile ID are used for tile flags
 // https://doc.mapeditor.org/en/stable/reference/global-tile-ids/#code-example
 const FLIPPED_HORIZONTALLY_FLAG uint32 = 0x80000000
@@ -96,101 +193,209 @@ const FLIPPED_VERTICALLY_FLAG uint32 = 0x40000000
 const FLIPPED_DIAGONALLY_FLAG uint32 = 0x20000000
 const ROTATED_HEXAGONAL_120_FLAG uint32 = 0x10000000
 
-func GetTileData(tileFileInput []byte) []tileDatum {
+// Load reads the .tmx file called name from fsys, along with any external tilesets it uses, and checks that it can be
+// played. Anything wrong with it is returned as an *Error.
+func Load(fsys fs.FS, name string) (*Map, error) {
+	input, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, &Error{File: name, Err: err}
+	}
 	var m Map
-	err := xml.Unmarshal(tileFileInput, &m)
+	err = xml.Unmarshal(input, &m)
 	if err != nil {
-		log.Fatal(err)
+		return nil, &Error{File: name, Err: err}
 	}
-
-	// Tiled maps must be infinite
-	if m.Infinite != 1 {
-		log.Fatal("Tiled maps must be infinite")
+	m.file = name
+	if m.Orientation != "orthogonal" {
+		return nil, &Error{File: name, Err: fmt.Errorf("%w: %v maps, only orthogonal ones", ErrUnsupported, m.Orientation)}
+	}
+	if m.Tilewidth <= 0 || m.Tileheight <= 0 {
+		return nil, &Error{File: name, Err: errors.New("tiles have no size")}
+	}
+	if len(m.Tileset) == 0 {
+		return nil, &Error{File: name, Err: errors.New("there are no tilesets")}
 	}
 
-	// Tiled maps must be base64 encoded and uncompressed
-	if m.Layer.Data.Encoding != "base64" || m.Layer.Data.Compression != "" {
-		log.Fatal("Tiled maps must be base64 encoded and uncompressed")
+	for i, tileset := range m.Tileset {
+		if tileset.Source == "" {
+			continue
+		}
+		// the tileset is in its own file, relative to the map
+		tsxName := path.Join(path.Dir(name), tileset.Source)
+		tsx, err := fs.ReadFile(fsys, tsxName)
+		if err != nil {
+			return nil, &Error{File: tsxName, Err: err}
+		}
+		var external Tileset
+		err = xml.Unmarshal(tsx, &external)
+		if err != nil {
+			return nil, &Error{File: tsxName, Err: err}
+		}
+		// the first gid is the only thing the map decides
+		external.Firstgid = tileset.Firstgid
+		m.Tileset[i] = external
+	}
+	for _, tileset := range m.Tileset {
+		if tileset.Columns == 0 {
+			return nil, &Error{File: name, Err: fmt.Errorf("%w: tileset %v is a collection of images, tilesets have to be a single image", ErrUnsupported, tileset.Name)}
+		}
 	}
+	m.tiles, err = m.loadTileInfo()
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
 
-	fmt.Println("Tilemap Width:", m.Layer.Width, "Height:", m.Layer.Height)
+// MapProperties returns the custom properties set on the map itself in Tiled, by name
+func (m *Map) MapProperties() map[string]string {
+	return m.Properties.Map()
+}
 
-	// Tiled stores data in chunks for infinite maps
-	for _, chunk := range m.Layer.Data.Chunk {
-		fmt.Println("Chunk X:", chunk.X, "Y:", chunk.Y)
+// tileSprite finds where a tile is in its tileset's image
+func tileSprite(tileset Tileset, globalTileId uint32) (string, int, int) {
+	offsetX := (globalTileId - uint32(tileset.Firstgid)) % uint32(tileset.Columns) * uint32(tileset.Tilewidth)
+	offsetY := (globalTileId - uint32(tileset.Firstgid)) / uint32(tileset.Columns) * uint32(tileset.Tileheight)
+	return tileset.Name, int(offsetX), int(offsetY)
+}
 
-		data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(chunk.Text))
-		if err != nil {
-			log.Fatal("error:", err)
+// tileset is the tileset a global tile ID belongs to
+func (m *Map) tileset(globalTileId uint32) (Tileset, error) {
+	for i := len(m.Tileset) - 1; i >= 0; i-- {
+		tileset := m.Tileset[i]
+		if uint32(tileset.Firstgid) <= globalTileId {
+			if tileset.Tilecount > 0 && globalTileId >= uint32(tileset.Firstgid+tileset.Tilecount) {
+				break
+			}
+			return tileset, nil
 		}
+	}
+	return Tileset{}, fmt.Errorf("tile %d isn't in any tileset", globalTileId)
+}
 
-		// https://doc.mapeditor.org/en/stable/reference/tmx-map-format/#data
-		// The data is an array of bytes, which should be interpreted as an array of unsigned 32-bit integers using little-endian byte ordering.
-		// https://doc.mapeditor.org/en/stable/reference/global-tile-ids/#
-		thisRow := 0
-		thisCol := -1
-		for tileIndex := 0; tileIndex < len(data); tileIndex += 4 {
-			// https://doc.mapeditor.org/en/stable/reference/global-tile-ids/#tile-flipping
-			// The highest four bits of the 32-bit GID are flip flags, and you will need to read and clear them before you can access the GID itself to identify the tile.
-			// Bit 32 is used for storing whether the tile is horizontally flipped, bit 31 is used for the vertically flipped tiles. In orthogonal and isometric maps, bit 30 indicates whether the tile is flipped (anti) diagonally, which enables tile rotation, and bit 29 can be ignored. In hexagonal maps, bit 30 indicates whether the tile is rotated 60 degrees clockwise, and bit 29 indicates 120 degrees clockwise rotation.
-
-			// https://doc.mapeditor.org/en/stable/reference/global-tile-ids/#code-example
-
-			// Read the GID in little-endian byte order
-			globalTileId := binary.LittleEndian.Uint32(data[tileIndex : tileIndex+4])
-
-			// Read out the flags
-			flipHorizontally := (globalTileId & FLIPPED_HORIZONTALLY_FLAG) != 0
-			flipVertically := (globalTileId & FLIPPED_VERTICALLY_FLAG) != 0
-			flippedDiagonally := (globalTileId & FLIPPED_DIAGONALLY_FLAG) != 0
-			rotatedHex120 := (globalTileId & ROTATED_HEXAGONAL_120_FLAG) != 0
-			_ = rotatedHex120 // (we don't use rotatedHex120 in this project)
-
-			// Clear highest four bits of the most significant byte (safe for a single byte)
-			// to clear off the flip flags
-			globalTileId &^= 0xF0000000
-
-			tileID := globalTileId
-
-			sprite := m.Tileset[0].Name // default sprite name
-			SpriteOffsetX := (tileID - 1) % uint32(m.Tileset[0].Columns) * uint32(m.Tileset[0].Tilewidth)
-			SpriteOffsetY := (tileID - 1) / uint32(m.Tileset[0].Columns) * uint32(m.Tileset[0].Tileheight)
-
-			// Resolve the tile
-			for i := len(m.Tileset) - 1; i >= 0; i-- {
-				tileset := m.Tileset[i]
-
-				if uint32(tileset.Firstgid) <= globalTileId {
-					sprite = tileset.Name
-					SpriteOffsetX = (tileID - uint32(tileset.Firstgid)) % uint32(tileset.Columns) * uint32(tileset.Tilewidth)
-					SpriteOffsetY = (tileID - uint32(tileset.Firstgid)) / uint32(tileset.Columns) * uint32(tileset.Tileheight)
-					break
-				}
+// TileData returns every tile on the map's visible tile layers
+func (m *Map) TileData() ([]Tile, error) {
+	var tileData []Tile
+	layers, err := m.tileLayers()
+	if err != nil {
+		return nil, err
+	}
+	first, last := -1, -1
+	for i, layer := range layers {
+		if layer.role == RoleCollision {
+			if first < 0 {
+				first = i
 			}
+			last = i
+		}
+	}
+
+	for i, layer := range layers {
+		var depth int
+		switch {
+		case first < 0:
+		case i < first:
+			depth = i - first
+		case i > last:
+			depth = i - last
+		}
+		depth = min(max(depth, -128), 127)
 
-			thisCol++
-			if thisCol >= chunk.Width {
-				thisCol = 0
-				thisRow++
+		// Tiled stores data in chunks for infinite maps
+		chunks := layer.Data.Chunk
+		if m.Infinite != 1 {
+			// and all in one go for finite maps, which is the same as one big chunk
+			chunks = []Chunk{{Text: layer.Data.Text, Width: layer.Width, Height: layer.Height, Tile: layer.Data.Tile}}
+		}
+		for _, chunk := range chunks {
+			fail := func(err error) error {
+				e := &Error{File: m.file, Layer: layer.Name, Err: err}
+				if m.Infinite == 1 {
+					e.Chunk = &image.Point{X: chunk.X, Y: chunk.Y}
+				}
+				return e
+			}
+			gids, err := decodeChunk(chunk, layer.Data.Encoding, layer.Data.Compression)
+			if err != nil {
+				return nil, fail(err)
+			}
+			if len(gids) != chunk.Width*chunk.Height {
+				return nil, fail(fmt.Errorf("there are %d tiles, there should be %d by %d", len(gids), chunk.Width, chunk.Height))
 			}
 
-			// store tile data
-			tileData = append(tileData, tileDatum{
-				ID:                   tileID,
-				Sprite:               sprite,
-				SpriteOffsetX:        int(SpriteOffsetX),
-				SpriteOffsetY:        int(SpriteOffsetY),
-				SpriteWidth:          m.Tilewidth,
-				SpriteHeight:         m.Tileheight,
-				SpriteFlipHorizontal: flipHorizontally,
-				SpriteFlipVertical:   flipVertically,
-				SpriteFlipDiagonal:   flippedDiagonally,
-				Row:                  thisRow + chunk.Y,
-				Col:                  thisCol + chunk.X,
-			})
+			// https://doc.mapeditor.org/en/stable/reference/tmx-map-format/#data
+			// https://doc.mapeditor.org/en/stable/reference/global-tile-ids/#
+			thisRow := 0
+			thisCol := -1
+			for _, globalTileId := range gids {
+				// https://doc.mapeditor.org/en/stable/reference/global-tile-ids/#tile-flipping
+				// The highest four bits of the 32-bit GID are flip flags, and you will need to read and clear them before you can access the GID itself to identify the tile.
+				// Bit 32 is used for storing whether the tile is horizontally flipped, bit 31 is used for the vertically flipped tiles. In orthogonal and isometric maps, bit 30 indicates whether the tile is flipped (anti) diagonally, which enables tile rotation, and bit 29 can be ignored. In hexagonal maps, bit 30 indicates whether the tile is rotated 60 degrees clockwise, and bit 29 indicates 120 degrees clockwise rotation.
+
+				// https://doc.mapeditor.org/en/stable/reference/global-tile-ids/#code-example
+
+				// Read out the flags
+				flipHorizontally := (globalTileId & FLIPPED_HORIZONTALLY_FLAG) != 0
+				flipVertically := (globalTileId & FLIPPED_VERTICALLY_FLAG) != 0
+				flippedDiagonally := (globalTileId & FLIPPED_DIAGONALLY_FLAG) != 0
+				rotatedHex120 := (globalTileId & ROTATED_HEXAGONAL_120_FLAG) != 0
+				_ = rotatedHex120 // (we don't use rotatedHex120 in this project)
+
+				// Clear highest four bits of the most significant byte (safe for a single byte)
+				// to clear off the flip flags
+				globalTileId &^= 0xF0000000
+
+				tileID := globalTileId
+
+				thisCol++
+				if thisCol >= chunk.Width {
+					thisCol = 0
+					thisRow++
+				}
+				if tileID == 0 {
+					continue // empty tile
+				}
+
+				// Resolve the tile
+				tileset, err := m.tileset(tileID)
+				if err != nil {
+					return nil, fail(fmt.Errorf("row %d, column %d: %w", thisRow, thisCol, err))
+				}
+				sprite, spriteOffsetX, spriteOffsetY := tileSprite(tileset, tileID)
+				info := m.tiles[tileID]
+				collision := make([]Shape, 0, len(info.collision))
+				for _, shape := range info.collision {
+					collision = append(collision, shape.flip(flipHorizontally, flipVertically, flippedDiagonally))
+				}
+
+				// store tile data
+				tileData = append(tileData, Tile{
+					ID:                   tileID,
+					Sprite:               sprite,
+					SpriteOffsetX:        spriteOffsetX,
+					SpriteOffsetY:        spriteOffsetY,
+					SpriteWidth:          tileset.Tilewidth,
+					SpriteHeight:         tileset.Tileheight,
+					SpriteFlipHorizontal: flipHorizontally,
+					SpriteFlipVertical:   flipVertically,
+					SpriteFlipDiagonal:   flippedDiagonally,
+					Row:                  thisRow + chunk.Y,
+					Col:                  thisCol + chunk.X,
+					Layer:                layer.Name,
+					Role:                 layer.role,
+					Depth:                int8(depth),
+					OffsetX:              layer.offsetX / float64(m.Tilewidth),
+					OffsetY:              layer.offsetY / float64(m.Tileheight),
+					ParallaxX:            layer.parallaxX,
+					ParallaxY:            layer.parallaxY,
+					Class:                info.class,
+					Properties:           info.properties,
+					Collision:            collision,
+				})
+			}
 		}
 	}
 
 	// https://doc.mapeditor.org/en/stable/reference/global-tile-ids/
-	return tileData
+	return tileData, nil
 }
@@ -0,0 +1,148 @@
+package tiled
+
+import (
+	"errors"
+	"image"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+)
+
+// load loads a map from testdata, failing the test if it doesn't load
+func load(t *testing.T, name string) *Map {
+	t.Helper()
+	m, err := Load(os.DirFS("testdata"), name)
+	if err != nil {
+		t.Fatalf("loading %v: %v", name, err)
+	}
+	return m
+}
+
+func TestZstd(t *testing.T) {
+	tiles, err := load(t, "zstd.tmx").TileData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		id       uint32
+		row, col int
+	}{{1, 0, 0}, {2, 0, 1}, {3, 1, 1}}
+	if len(tiles) != len(want) {
+		t.Fatalf("got %d tiles, want %d", len(tiles), len(want))
+	}
+	for i, w := range want {
+		if tiles[i].ID != w.id || tiles[i].Row != w.row || tiles[i].Col != w.col {
+			t.Errorf("tile %d is %d at row %d, column %d, want %d at row %d, column %d",
+				i, tiles[i].ID, tiles[i].Row, tiles[i].Col, w.id, w.row, w.col)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		file     string
+		errFile  string       // the file the error is in, if it isn't file
+		layer    string       // the layer the error is in, if it is in one
+		chunk    *image.Point // the chunk the error is in, if it is in one
+		contains string       // something the error says
+		is       error        // what it wraps, like ErrUnsupported
+	}{
+		{file: "bad-xml.tmx", contains: "XML syntax error"},
+		{file: "isometric.tmx", contains: "isometric maps", is: ErrUnsupported},
+		{file: "bad-encoding.tmx", layer: "ground", contains: `unknown tile layer encoding "hex"`},
+		{file: "bad-compression.tmx", layer: "ground", contains: `unknown tile layer compression "lzma"`},
+		{file: "bad-base64.tmx", layer: "ground", contains: "illegal base64 data"},
+		{file: "wrong-count.tmx", layer: "ground", contains: "there are 3 tiles, there should be 2 by 2"},
+		{file: "unknown-gid.tmx", layer: "ground", contains: "row 1, column 1: tile 9 isn't in any tileset"},
+		{file: "missing-tileset.tmx", errFile: "missing.tsx", contains: "open missing.tsx", is: fs.ErrNotExist},
+		{file: "bad-chunk.tmx", layer: "ground", chunk: &image.Point{X: 2, Y: 0}, contains: "there are 3 tiles"},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			m, err := Load(os.DirFS("testdata"), test.file)
+			if err == nil {
+				_, err = m.TileData()
+			}
+			if err == nil {
+				t.Fatal("it loaded")
+			}
+			var located *Error
+			if !errors.As(err, &located) {
+				t.Fatalf("%v is a %T, not a *tiled.Error", err, err)
+			}
+			wantFile := test.file
+			if test.errFile != "" {
+				wantFile = test.errFile
+			}
+			if located.File != wantFile {
+				t.Errorf("the error is in %q, want %q", located.File, wantFile)
+			}
+			if located.Layer != test.layer {
+				t.Errorf("the error is on layer %q, want %q", located.Layer, test.layer)
+			}
+			switch {
+			case test.chunk == nil && located.Chunk != nil:
+				t.Errorf("the error is in chunk %v, want no chunk", *located.Chunk)
+			case test.chunk != nil && (located.Chunk == nil || *located.Chunk != *test.chunk):
+				t.Errorf("the error is in chunk %v, want %v", located.Chunk, *test.chunk)
+			}
+			if !strings.Contains(err.Error(), test.contains) {
+				t.Errorf("%q doesn't say %q", err, test.contains)
+			}
+			if test.is != nil && !errors.Is(err, test.is) {
+				t.Errorf("%q isn't %q", err, test.is)
+			}
+			if test.is != ErrUnsupported && errors.Is(err, ErrUnsupported) {
+				t.Errorf("%q says it is unsupported", err)
+			}
+		})
+	}
+}
+
+func TestLoadTwice(t *testing.T) {
+	var loaded [2][]Tile
+	for i := range loaded {
+		m := load(t, "good.tmx")
+		tiles, err := m.TileData()
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded[i] = tiles
+		if got := m.MapProperties()["mode"]; got != "ffa" {
+			t.Errorf("the mode property is %q, want ffa", got)
+		}
+	}
+
+	// 4 decoration tiles, and 3 on the ground layer
+	if len(loaded[0]) != 7 || len(loaded[1]) != 7 {
+		t.Fatalf("got %d tiles then %d, want 7 each time", len(loaded[0]), len(loaded[1]))
+	}
+	type place struct {
+		layer    string
+		row, col int
+	}
+	seen := make(map[place]bool)
+	for i, tile := range loaded[0] {
+		p := place{tile.Layer, tile.Row, tile.Col}
+		if seen[p] {
+			t.Errorf("there are two tiles at row %d, column %d on %v", tile.Row, tile.Col, tile.Layer)
+		}
+		seen[p] = true
+		again := loaded[1][i]
+		if again.ID != tile.ID || again.Layer != tile.Layer || again.Row != tile.Row || again.Col != tile.Col {
+			t.Errorf("tile %d is %d at %v the first time and %d at %v the second", i, tile.ID, p, again.ID, place{again.Layer, again.Row, again.Col})
+		}
+	}
+
+	// the external tileset's properties come through, for the tile with ID 1 in it
+	for _, tile := range loaded[0] {
+		want := ""
+		if tile.ID == 2 {
+			want = "0.1"
+		}
+		if got := tile.Properties["friction"]; got != want {
+			t.Errorf("tile %d has friction %q, want %q", tile.ID, got, want)
+		}
+	}
+}
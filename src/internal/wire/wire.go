@@ -0,0 +1,458 @@
+package wire
+
+// This is the binary encoding of WorldData. It carries exactly what the JSON encoding does, in fewer bytes.
+// The JSON encoding is still available for debugging, the protocol is picked when the websocket connects.
+//
+// Every number is little-endian. A frame looks like:
+//
+//	u32 length of everything after this field
+//	u8  'G'
+//	u8  Version
+//	u64 tick
+//	u64 baseline
+//	u8  game data flags (bit 0 portal, bit 1 the player id is not a UUID, bit 2 there is a scoreboard, bit 3 there is a match state)
+//	id  player UUID
+//	u16 tick rate
+//	u64 ship input sequence, then f64 ship x, y, vx, vy, angle, angular velocity
+//	u16 health, u16 max health, f32 seconds until respawn
+//	u8  session token length, and bytes
+//	if there is a scoreboard, u16 line count, then each line:
+//	    u8  flags (bit 0 the id is not a UUID)
+//	    id  player UUID
+//	    u8  name length, and bytes
+//	    u8  team length, and bytes
+//	    u16 kills, deaths, assists, turrets, u32 shots fired, shots hit, u16 pickups, f32 seconds alive
+//	if there is a match state:
+//	    u8  mode length, and bytes
+//	    u8  map length, and bytes
+//	    u16 round, f32 seconds left, f32 seconds until the next round
+//	    u8  next map length, and bytes
+//	    u8  result length, and bytes
+//	    u8  team count, then each team as a u8 length and bytes, and a u16 score
+//	u16 sprite count, then each sprite name as a u8 length and bytes
+//	u32 object count, then each object:
+//	    u8  flags (see the flag constants below)
+//	    id  UUID
+//	    u16 shared_structs.FieldMask
+//	    i32 x                                  if FieldX
+//	    i32 y                                  if FieldY
+//	    f32 angle                              if FieldAngle
+//	    u16 sprite index, u16 x0, y0, x1, y1   if FieldSprite
+//	    u8  name length, and bytes             if FieldName
+//	    i8  depth, f32 parallax x, y           if FieldLayer
+//
+// An id is 16 raw bytes if it is a UUID, otherwise a u8 length and bytes, which the object flags say.
+
+import (
+	"Geomyidae/internal/shared_structs"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// Version is bumped whenever the layout changes.
+// Clients send it in their hello too, so one that doesn't match is turned away with a reason instead of failing to decode.
+const Version = 10
+
+// Websocket subprotocols the client can ask for when it connects.
+// The binary protocol name includes the version, so a client and server that disagree fall back to JSON.
+const (
+	BinaryProtocol = "geomyidae.v10.bin"
+	JSONProtocol   = "geomyidae.v1.json"
+)
+
+const magic = 'G'
+
+const (
+	flagDelete = 1 << iota
+	flagFlipHorizontal
+	flagFlipVertical
+	flagFlipDiagonal
+	flagStringID
+)
+
+var le = binary.LittleEndian
+
+// Encode returns data in the binary encoding
+func Encode(data *shared_structs.WorldData) []byte {
+	// sprite names are sent once per frame and referred to by index
+	spriteIndex := map[string]int{}
+	var sprites []string
+	for i := range data.Objects {
+		obj := &data.Objects[i]
+		if obj.Delete || fields(obj)&shared_structs.FieldSprite == 0 {
+			continue
+		}
+		if _, ok := spriteIndex[obj.Sprite]; !ok {
+			spriteIndex[obj.Sprite] = len(sprites)
+			sprites = append(sprites, obj.Sprite)
+		}
+	}
+
+	buf := make([]byte, 4, 64+len(data.Objects)*32)
+	buf = append(buf, magic, Version)
+	buf = le.AppendUint64(buf, data.Tick)
+	buf = le.AppendUint64(buf, data.Baseline)
+	var gameFlags byte
+	if data.GameData.Portal {
+		gameFlags |= 1
+	}
+	if !isUUID(data.GameData.PlayerUUID) {
+		gameFlags |= 2
+	}
+	if len(data.Scoreboard) > 0 {
+		gameFlags |= 4
+	}
+	if data.Match != nil {
+		gameFlags |= 8
+	}
+	buf = append(buf, gameFlags)
+	buf = appendID(buf, data.GameData.PlayerUUID)
+	buf = le.AppendUint16(buf, uint16(data.GameData.TickRate))
+	ship := data.GameData.Ship
+	buf = le.AppendUint64(buf, ship.Seq)
+	for _, f := range []float64{ship.X, ship.Y, ship.VX, ship.VY, ship.Angle, ship.AngularVelocity} {
+		buf = le.AppendUint64(buf, math.Float64bits(f))
+	}
+	buf = le.AppendUint16(buf, uint16(max(data.GameData.Health, 0)))
+	buf = le.AppendUint16(buf, uint16(data.GameData.MaxHealth))
+	buf = le.AppendUint32(buf, math.Float32bits(float32(data.GameData.RespawnIn)))
+	buf = appendString(buf, data.GameData.Session)
+	if len(data.Scoreboard) > 0 {
+		buf = appendScoreboard(buf, data.Scoreboard)
+	}
+	if data.Match != nil {
+		buf = appendMatch(buf, data.Match)
+	}
+
+	buf = le.AppendUint16(buf, uint16(len(sprites)))
+	for _, sprite := range sprites {
+		buf = appendString(buf, sprite)
+	}
+
+	buf = le.AppendUint32(buf, uint32(len(data.Objects)))
+	for i := range data.Objects {
+		obj := &data.Objects[i]
+		mask := fields(obj)
+		var flags byte
+		if obj.Delete {
+			flags |= flagDelete
+		}
+		if obj.SpriteFlipHorizontal {
+			flags |= flagFlipHorizontal
+		}
+		if obj.SpriteFlipVertical {
+			flags |= flagFlipVertical
+		}
+		if obj.SpriteFlipDiagonal {
+			flags |= flagFlipDiagonal
+		}
+		if !isUUID(obj.UUID) {
+			flags |= flagStringID
+		}
+		buf = append(buf, flags)
+		buf = appendID(buf, obj.UUID)
+		buf = le.AppendUint16(buf, uint16(mask))
+		if mask&shared_structs.FieldX != 0 {
+			buf = le.AppendUint32(buf, uint32(int32(obj.X)))
+		}
+		if mask&shared_structs.FieldY != 0 {
+			buf = le.AppendUint32(buf, uint32(int32(obj.Y)))
+		}
+		if mask&shared_structs.FieldAngle != 0 {
+			buf = le.AppendUint32(buf, math.Float32bits(float32(obj.Angle)))
+		}
+		if mask&shared_structs.FieldSprite != 0 {
+			buf = le.AppendUint16(buf, uint16(spriteIndex[obj.Sprite]))
+			buf = le.AppendUint16(buf, uint16(obj.SpriteOffsetX))
+			buf = le.AppendUint16(buf, uint16(obj.SpriteOffsetY))
+			buf = le.AppendUint16(buf, uint16(obj.SpriteWidth))
+			buf = le.AppendUint16(buf, uint16(obj.SpriteHeight))
+		}
+		if mask&shared_structs.FieldName != 0 {
+			buf = appendString(buf, obj.Name)
+		}
+		if mask&shared_structs.FieldLayer != 0 {
+			buf = append(buf, byte(obj.Depth))
+			buf = le.AppendUint32(buf, math.Float32bits(float32(obj.ParallaxX)))
+			buf = le.AppendUint32(buf, math.Float32bits(float32(obj.ParallaxY)))
+		}
+	}
+
+	le.PutUint32(buf, uint32(len(buf)-4))
+	return buf
+}
+
+func appendScoreboard(buf []byte, scores []shared_structs.PlayerScore) []byte {
+	buf = le.AppendUint16(buf, uint16(len(scores)))
+	for _, score := range scores {
+		var flags byte
+		if !isUUID(score.ID) {
+			flags |= 1
+		}
+		buf = append(buf, flags)
+		buf = appendID(buf, score.ID)
+		buf = appendString(buf, score.Name)
+		buf = appendString(buf, score.Team)
+		for _, n := range []int{score.Kills, score.Deaths, score.Assists, score.Turrets} {
+			buf = le.AppendUint16(buf, uint16(n))
+		}
+		buf = le.AppendUint32(buf, uint32(score.ShotsFired))
+		buf = le.AppendUint32(buf, uint32(score.ShotsHit))
+		buf = le.AppendUint16(buf, uint16(score.Pickups))
+		buf = le.AppendUint32(buf, math.Float32bits(float32(score.TimeAlive)))
+	}
+	return buf
+}
+
+func appendMatch(buf []byte, match *shared_structs.MatchState) []byte {
+	buf = appendString(buf, match.Mode)
+	buf = appendString(buf, match.Map)
+	buf = le.AppendUint16(buf, uint16(match.Round))
+	buf = le.AppendUint32(buf, math.Float32bits(float32(match.TimeLeft)))
+	buf = le.AppendUint32(buf, math.Float32bits(float32(match.NextRound)))
+	buf = appendString(buf, match.NextMap)
+	buf = appendString(buf, match.Result)
+	buf = append(buf, byte(len(match.Teams)))
+	for _, team := range match.Teams {
+		buf = appendString(buf, team.Team)
+		buf = le.AppendUint16(buf, uint16(team.Score))
+	}
+	return buf
+}
+
+// fields is the mask actually written for obj, a full object has every field written out
+func fields(obj *shared_structs.GameObject) shared_structs.FieldMask {
+	if obj.Delete {
+		return 0
+	}
+	if obj.Fields == 0 {
+		return shared_structs.AllFields
+	}
+	return obj.Fields
+}
+
+func isUUID(id string) bool {
+	// only the canonical form round trips
+	parsed, err := uuid.Parse(id)
+	return err == nil && parsed.String() == id
+}
+
+func appendID(buf []byte, id string) []byte {
+	if isUUID(id) {
+		parsed := uuid.MustParse(id)
+		return append(buf, parsed[:]...)
+	}
+	return appendString(buf, id)
+}
+
+func appendString(buf []byte, s string) []byte {
+	if len(s) > math.MaxUint8 {
+		s = s[:math.MaxUint8]
+	}
+	buf = append(buf, byte(len(s)))
+	return append(buf, s...)
+}
+
+var ErrShortFrame = errors.New("wire: frame is truncated")
+
+// Decode reads a frame written by Encode
+func Decode(frame []byte) (*shared_structs.WorldData, error) {
+	r := reader{buf: frame}
+	length := r.u32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if int(length) != len(frame)-4 {
+		return nil, fmt.Errorf("wire: frame says it is %d bytes but it is %d", length, len(frame)-4)
+	}
+	if m := r.u8(); m != magic {
+		return nil, fmt.Errorf("wire: bad magic byte %#x", m)
+	}
+	if v := r.u8(); v != Version {
+		return nil, fmt.Errorf("wire: unsupported version %d, expected %d", v, Version)
+	}
+
+	data := &shared_structs.WorldData{}
+	data.Tick = r.u64()
+	data.Baseline = r.u64()
+	gameFlags := r.u8()
+	data.GameData.Portal = gameFlags&1 != 0
+	if gameFlags&2 != 0 {
+		data.GameData.PlayerUUID = r.string()
+	} else {
+		data.GameData.PlayerUUID = r.uuid()
+	}
+	data.GameData.TickRate = int(r.u16())
+	ship := &data.GameData.Ship
+	ship.Seq = r.u64()
+	for _, f := range []*float64{&ship.X, &ship.Y, &ship.VX, &ship.VY, &ship.Angle, &ship.AngularVelocity} {
+		*f = math.Float64frombits(r.u64())
+	}
+	data.GameData.Health = int(r.u16())
+	data.GameData.MaxHealth = int(r.u16())
+	data.GameData.RespawnIn = float64(math.Float32frombits(r.u32()))
+	data.GameData.Session = r.string()
+	if gameFlags&4 != 0 {
+		data.Scoreboard = r.scoreboard()
+	}
+	if gameFlags&8 != 0 {
+		data.Match = r.match()
+	}
+
+	sprites := make([]string, r.u16())
+	for i := range sprites {
+		sprites[i] = r.string()
+	}
+
+	count := r.u32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	// every object is at least 4 bytes, so a bogus count can't make us allocate much
+	if int(count) > r.remaining()/4 {
+		return nil, ErrShortFrame
+	}
+	data.Objects = make([]shared_structs.GameObject, count)
+	for i := range data.Objects {
+		obj := &data.Objects[i]
+		flags := r.u8()
+		if flags&flagStringID != 0 {
+			obj.UUID = r.string()
+		} else {
+			obj.UUID = r.uuid()
+		}
+		obj.Delete = flags&flagDelete != 0
+		obj.SpriteFlipHorizontal = flags&flagFlipHorizontal != 0
+		obj.SpriteFlipVertical = flags&flagFlipVertical != 0
+		obj.SpriteFlipDiagonal = flags&flagFlipDiagonal != 0
+		mask := shared_structs.FieldMask(r.u16())
+		if !obj.Delete && mask != shared_structs.AllFields {
+			obj.Fields = mask
+		}
+		if mask&shared_structs.FieldX != 0 {
+			obj.X = int(int32(r.u32()))
+		}
+		if mask&shared_structs.FieldY != 0 {
+			obj.Y = int(int32(r.u32()))
+		}
+		if mask&shared_structs.FieldAngle != 0 {
+			obj.Angle = shared_structs.RoundedFloat2(math.Float32frombits(r.u32()))
+		}
+		if mask&shared_structs.FieldSprite != 0 {
+			index := int(r.u16())
+			if index >= len(sprites) {
+				return nil, fmt.Errorf("wire: object %d uses sprite %d of %d", i, index, len(sprites))
+			}
+			obj.Sprite = sprites[index]
+			obj.SpriteOffsetX = int(r.u16())
+			obj.SpriteOffsetY = int(r.u16())
+			obj.SpriteWidth = int(r.u16())
+			obj.SpriteHeight = int(r.u16())
+		}
+		if mask&shared_structs.FieldName != 0 {
+			obj.Name = r.string()
+		}
+		if mask&shared_structs.FieldLayer != 0 {
+			obj.Depth = int8(r.u8())
+			obj.ParallaxX = shared_structs.RoundedFloat2(math.Float32frombits(r.u32()))
+			obj.ParallaxY = shared_structs.RoundedFloat2(math.Float32frombits(r.u32()))
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return data, nil
+}
+
+// reader reads fixed width fields, and remembers if it ran off the end so that only the end result needs checking
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) remaining() int {
+	return len(r.buf)
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil || len(r.buf) < n {
+		r.err = ErrShortFrame
+		return make([]byte, n)
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *reader) u8() byte {
+	return r.next(1)[0]
+}
+
+func (r *reader) u16() uint16 {
+	return le.Uint16(r.next(2))
+}
+
+func (r *reader) u32() uint32 {
+	return le.Uint32(r.next(4))
+}
+
+func (r *reader) u64() uint64 {
+	return le.Uint64(r.next(8))
+}
+
+func (r *reader) string() string {
+	return string(r.next(int(r.u8())))
+}
+
+func (r *reader) scoreboard() []shared_structs.PlayerScore {
+	count := int(r.u16())
+	// every line is at least 26 bytes
+	if count > r.remaining()/26 {
+		r.err = ErrShortFrame
+		return nil
+	}
+	scores := make([]shared_structs.PlayerScore, count)
+	for i := range scores {
+		score := &scores[i]
+		if r.u8()&1 != 0 {
+			score.ID = r.string()
+		} else {
+			score.ID = r.uuid()
+		}
+		score.Name = r.string()
+		score.Team = r.string()
+		for _, n := range []*int{&score.Kills, &score.Deaths, &score.Assists, &score.Turrets} {
+			*n = int(r.u16())
+		}
+		score.ShotsFired = int(r.u32())
+		score.ShotsHit = int(r.u32())
+		score.Pickups = int(r.u16())
+		score.TimeAlive = shared_structs.RoundedFloat2(math.Float32frombits(r.u32()))
+	}
+	return scores
+}
+
+func (r *reader) match() *shared_structs.MatchState {
+	match := &shared_structs.MatchState{}
+	match.Mode = r.string()
+	match.Map = r.string()
+	match.Round = int(r.u16())
+	match.TimeLeft = shared_structs.RoundedFloat2(math.Float32frombits(r.u32()))
+	match.NextRound = shared_structs.RoundedFloat2(math.Float32frombits(r.u32()))
+	match.NextMap = r.string()
+	match.Result = r.string()
+	teams := int(r.u8())
+	for range teams {
+		match.Teams = append(match.Teams, shared_structs.TeamScore{Team: r.string(), Score: int(r.u16())})
+	}
+	return match
+}
+
+func (r *reader) uuid() string {
+	id, _ := uuid.FromBytes(r.next(16))
+	return id.String()
+}
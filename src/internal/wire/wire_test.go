@@ -0,0 +1,184 @@
+package wire
+
+import (
+	"Geomyidae/internal/shared_structs"
+	"reflect"
+	"testing"
+)
+
+const (
+	alice = "6f1c2a4e-8d3b-4c5a-9e7f-0a1b2c3d4e5f"
+	bob   = "0b9d8c7a-6e5f-4a3b-8c2d-1e0f9a8b7c6d"
+)
+
+var ship = shared_structs.GameObject{
+	UUID:          alice,
+	X:             -320,
+	Y:             1280,
+	Angle:         1.5,
+	Sprite:        "spaceShooterRedux",
+	SpriteOffsetX: 325,
+	SpriteWidth:   98,
+	SpriteHeight:  75,
+	Name:          "alice",
+}
+
+var tile = shared_structs.GameObject{
+	UUID:               "tile-3-4", // not a UUID, so it is sent as a string
+	X:                  192,
+	Y:                  256,
+	Sprite:             "tiles",
+	SpriteOffsetX:      64,
+	SpriteOffsetY:      128,
+	SpriteWidth:        64,
+	SpriteHeight:       64,
+	SpriteFlipVertical: true,
+	SpriteFlipDiagonal: true,
+	Depth:              -1,
+	ParallaxX:          0.5,
+	ParallaxY:          0.25,
+}
+
+var gameData = shared_structs.GameData{
+	Portal:     true,
+	PlayerUUID: alice,
+	TickRate:   50,
+	Ship:       shared_structs.ShipState{Seq: 1234, X: -5.125, Y: 20.0625, VX: 0.1, VY: -3, Angle: 3.14159, AngularVelocity: -0.5},
+	Health:     65,
+	MaxHealth:  100,
+	RespawnIn:  2.5,
+	Session:    "secret-session-token",
+}
+
+var scoreboard = []shared_structs.PlayerScore{
+	{ID: alice, Name: "alice", Team: "red", Kills: 3, Deaths: 1, Assists: 2, Turrets: 4, ShotsFired: 100000, ShotsHit: 42, Pickups: 5, TimeAlive: 61.5},
+	{ID: "bot", Name: "bot"},
+}
+
+var match = &shared_structs.MatchState{
+	Mode:      "tdm",
+	Map:       "test-one",
+	Round:     2,
+	TimeLeft:  300.25,
+	Teams:     []shared_structs.TeamScore{{Team: "red", Score: 7}, {Team: "blue", Score: 9}},
+	Result:    "red wins",
+	NextRound: 5,
+	NextMap:   "test-two",
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		data shared_structs.WorldData
+	}{
+		{
+			name: "full snapshot",
+			data: shared_structs.WorldData{
+				Tick:       100,
+				Objects:    []shared_structs.GameObject{ship, tile},
+				GameData:   gameData,
+				Scoreboard: scoreboard,
+				Match:      match,
+			},
+		},
+		{
+			name: "delta",
+			data: shared_structs.WorldData{
+				Tick:     101,
+				Baseline: 99,
+				Objects: []shared_structs.GameObject{
+					{UUID: alice, X: -300, Angle: 1.75, Fields: shared_structs.FieldX | shared_structs.FieldAngle},
+					{UUID: bob, Sprite: "spaceShooterRedux", SpriteOffsetY: 739, SpriteWidth: 98, SpriteHeight: 75, Name: "bob",
+						Fields: shared_structs.FieldSprite | shared_structs.FieldName},
+					{UUID: "tile-3-4", Depth: 2, ParallaxX: 1, Fields: shared_structs.FieldLayer},
+				},
+				GameData: shared_structs.GameData{PlayerUUID: alice, TickRate: 50, Health: 100, MaxHealth: 100},
+			},
+		},
+		{
+			name: "removed",
+			data: shared_structs.WorldData{
+				Tick:     102,
+				Baseline: 101,
+				Objects: []shared_structs.GameObject{
+					{UUID: bob, Delete: true},
+					{UUID: "tile-3-4", Delete: true},
+					ship,
+				},
+				GameData: gameData,
+			},
+		},
+		{
+			name: "only a scoreboard",
+			data: shared_structs.WorldData{Tick: 150, Baseline: 149, GameData: gameData, Scoreboard: scoreboard},
+		},
+		{
+			name: "only a match",
+			data: shared_structs.WorldData{Tick: 150, Baseline: 149, GameData: gameData, Match: &shared_structs.MatchState{Mode: "ffa", Round: 1}},
+		},
+		{
+			name: "player id isn't a UUID",
+			data: shared_structs.WorldData{Tick: 1, GameData: shared_structs.GameData{PlayerUUID: "spectator"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Decode(Encode(&test.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := test.data
+			// an empty list decodes as empty rather than nil, which means the same
+			if len(want.Objects) == 0 {
+				want.Objects = []shared_structs.GameObject{}
+			}
+			if !reflect.DeepEqual(*got, want) {
+				t.Errorf("got  %+v\nwant %+v", *got, want)
+			}
+		})
+	}
+}
+
+// TestDecodeErrors checks broken frames are errors, and never panics
+func TestDecodeErrors(t *testing.T) {
+	frame := Encode(&shared_structs.WorldData{
+		Tick:       100,
+		Objects:    []shared_structs.GameObject{ship, tile, {UUID: bob, Delete: true}},
+		GameData:   gameData,
+		Scoreboard: scoreboard,
+		Match:      match,
+	})
+
+	for n := range len(frame) {
+		_, err := Decode(frame[:n])
+		if err == nil {
+			t.Errorf("the first %d of %d bytes decoded", n, len(frame))
+		}
+	}
+	// the length is right, but what it says is cut short, like a count bigger than what follows
+	for n := 4; n < len(frame); n++ {
+		short := append([]byte(nil), frame[:n]...)
+		le.PutUint32(short, uint32(n-4))
+		_, err := Decode(short)
+		if err == nil {
+			t.Errorf("the first %d of %d bytes decoded with the length fixed up", n, len(frame))
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(frame []byte) []byte
+	}{
+		{"wrong version", func(frame []byte) []byte { frame[5] = Version - 1; return frame }},
+		{"bad magic", func(frame []byte) []byte { frame[4] = 'X'; return frame }},
+		{"too long", func(frame []byte) []byte { return append(frame, 0) }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(test.change(append([]byte(nil), frame...)))
+			if err == nil {
+				t.Error("it decoded")
+			}
+		})
+	}
+}
@@ -4,56 +4,53 @@ import (
 	"Geomyidae/internal/constants"
 	"Geomyidae/internal/shared_structs"
 	"Geomyidae/server/bullet"
+	"Geomyidae/server/collision"
+	"Geomyidae/server/combat"
+	"Geomyidae/server/entity"
+	"errors"
 	"math"
-	"time"
 
 	"github.com/google/uuid"
-	"github.com/jakecoffman/cp/v2"
 )
 
 type Bomb struct {
 	*shared_structs.GameObject
-	expirationDate time.Time
-	detCount       float64
-	detMax         float64
-	det            bool
+	fuse     float64
+	detCount float64
+	detMax   float64
+	det      bool
 }
 
-func NewBomb(x, y float64) *Bomb {
-	body := cp.NewBody(1, 1)
-	shape := cp.NewBox(body, 1, 1, 0)
-	shape.SetElasticity(0.25)
-	shape.SetDensity(0.5)
-	shape.SetFriction(1.0)
-	body.AddShape(shape)
-	body.SetPosition(cp.Vector{X: x / 64, Y: y / 64})
+// NewBomb drops a bomb at x, y in pixels. Its shrapnel belongs to owner.
+func NewBomb(owner *shared_structs.GameObject, x, y float64) *Bomb {
+	archetype := entity.ArchetypeOf(string(constants.Bomb))
+	body, shape := archetype.NewBody(x/constants.MetersToPixels, y/constants.MetersToPixels)
 	gameObject := shared_structs.GameObject{
-		X:             int(x),
-		Y:             int(y),
-		Sprite:        "spaceShooterRedux",
-		SpriteOffsetX: 20,
-		SpriteOffsetY: 0,
-		SpriteWidth:   16,
-		SpriteHeight:  16,
-		UUID:          uuid.New().String(),
-		Body:          body,
-		Shape:         shape,
-		IsStatic:      false,
-		Identity:      constants.Bomb,
+		X:        int(x),
+		Y:        int(y),
+		UUID:     uuid.New().String(),
+		Body:     body,
+		Shape:    shape,
+		IsStatic: false,
+		Identity: constants.Bomb,
+		Owner:    combat.OwnerOf(owner),
+		Team:     owner.Team,
 	}
+	archetype.Dress(&gameObject)
 	body.UserData = &gameObject
 
 	return &Bomb{
-		GameObject:     &gameObject,
-		expirationDate: time.Now().Add(time.Duration(1) * time.Second),
-		detCount:       0,
-		detMax:         36,
-		det:            false,
+		GameObject: &gameObject,
+		fuse:       archetype.Lifetime, // seconds
+		detCount:   0,
+		detMax:     float64(archetype.Shrapnel),
+		det:        false,
 	}
 }
-func (b *Bomb) ApplyBehavior(deltaTime float64, spawnerPipeline chan shared_structs.HasBehavior) {
+func (b *Bomb) ApplyBehavior(tick shared_structs.Tick, spawnerPipeline chan shared_structs.HasBehavior) {
 	if !b.det {
-		if b.expirationDate.UnixMilli() < time.Now().UnixMilli() {
+		b.fuse -= tick.DeltaTime
+		if b.fuse <= 0 {
 			b.det = true
 		}
 	}
@@ -62,23 +59,31 @@ func (b *Bomb) ApplyBehavior(deltaTime float64, spawnerPipeline chan shared_stru
 	} else if b.det {
 		degree := (math.Pi * 2) / b.detMax
 		b.Body.SetAngle(degree * b.detCount)
-		newBullet := bullet.NewBullet(b.GetObject())
+		newBullet := bullet.NewShrapnel(b.GetObject())
 		select {
 		case spawnerPipeline <- newBullet:
 		default:
 		}
 		b.detCount++
 	}
-	b.Body.EachArbiter(func(arbiter *cp.Arbiter) {
-		_, bodB := arbiter.Bodies()
-		if ptr, ok := bodB.UserData.(*shared_structs.GameObject); ok {
-			if ptr.Identity == constants.Bullet {
-				b.Delete = true
-			}
-		}
-	})
+}
+
+// Shot is the collision rule for a bullet hitting a bomb. The bomb is gone, without going off.
+func Shot(c collision.Contact) {
+	c.A.Delete = true
 }
 
 func (b *Bomb) GetObject() *shared_structs.GameObject {
 	return b.GameObject
 }
+
+func init() {
+	// the fuse has to burn down, and the shrapnel is spread evenly around it
+	entity.RequiresPositive(string(constants.Bomb), "lifetime", "shrapnel")
+	entity.Register(constants.Bomb, func(params entity.Params) (shared_structs.HasBehavior, error) {
+		if params.Owner == nil {
+			return nil, errors.New("it needs an owner to credit its kills to")
+		}
+		return NewBomb(params.Owner, params.X*constants.MetersToPixels, params.Y*constants.MetersToPixels), nil
+	})
+}
@@ -3,48 +3,51 @@ package bullet
 import (
 	"Geomyidae/internal/constants"
 	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/collision"
+	"Geomyidae/server/combat"
+	"Geomyidae/server/entity"
+	"errors"
 	"math"
-	"time"
 
 	"github.com/google/uuid"
-	"github.com/jakecoffman/cp/v2"
 )
 
 type Bullet struct {
 	*shared_structs.GameObject
-	expirationDate time.Time
+	lifetime float64
 }
 
+// muzzle is how far in front of the middle of whatever fires it a bullet appears, in meters
+const muzzle = 1.0
+
+// NewBullet fires a bullet from gameObj. Its speed, damage and how long it flies are in the bullet archetype.
 func NewBullet(gameObj *shared_structs.GameObject) *Bullet {
-	body := cp.NewBody(1, 1)
-	shape := cp.NewCircle(body, 0.125, cp.Vector{X: 0, Y: 0})
-	shape.SetElasticity(0.25)
-	shape.SetDensity(50.5)
-	shape.SetFriction(1.0)
-	body.AddShape(shape)
+	return newBullet(gameObj, constants.Bullet, entity.ArchetypeOf("bullet"))
+}
+
+// NewShrapnel is a bullet thrown out by an exploding bomb. Bombs throw it out in a ring, so each piece usually hurts less.
+func NewShrapnel(gameObj *shared_structs.GameObject) *Bullet {
+	return newBullet(gameObj, constants.Shrapnel, entity.ArchetypeOf("shrapnel"))
+}
+
+// newBullet fires a bullet from gameObj. The bullet belongs to whoever owns gameObj, and is on its team.
+func newBullet(gameObj *shared_structs.GameObject, identity constants.UserDataCode, archetype entity.Archetype) *Bullet {
 	pos := gameObj.Body.Position()
-	x := pos.X
-	y := pos.Y
 	angle := gameObj.Body.Angle()
-	thrust := 35.0
-	offset := 1.0
-	x = x + math.Sin(angle)*offset
-	y = y + math.Cos(angle)*(offset*-1)
-	body.SetVelocity(math.Sin(angle)*thrust, math.Cos(angle)*(-1*thrust))
-	body.SetPosition(cp.Vector{X: x, Y: y})
+	body, shape := archetype.NewBody(pos.X+math.Sin(angle)*muzzle, pos.Y-math.Cos(angle)*muzzle)
+	body.SetVelocity(math.Sin(angle)*archetype.Speed, -math.Cos(angle)*archetype.Speed)
 	newBullet := Bullet{GameObject: &shared_structs.GameObject{
-		Sprite:        "spaceShooterRedux",
-		SpriteOffsetX: 0,
-		SpriteOffsetY: 0,
-		SpriteWidth:   16,
-		SpriteHeight:  16,
-		Angle:         shared_structs.RoundedFloat2(body.Angle()),
-		UUID:          uuid.New().String(),
-		Body:          body,
-		Shape:         shape,
-		Identity:      constants.Bullet,
+		Angle:    shared_structs.RoundedFloat2(body.Angle()),
+		UUID:     uuid.New().String(),
+		Body:     body,
+		Shape:    shape,
+		Identity: identity,
+		Damage:   archetype.Damage,
+		Owner:    combat.OwnerOf(gameObj),
+		Team:     gameObj.Team,
 	},
-		expirationDate: time.Now().Add(time.Second * 5)}
+		lifetime: archetype.Lifetime}
+	archetype.Dress(newBullet.GameObject)
 	body.UserData = newBullet.GameObject
 
 	return &newBullet
@@ -54,16 +57,28 @@ func (b *Bullet) GetObject() *shared_structs.GameObject {
 	return b.GameObject
 }
 
-func (b *Bullet) ApplyBehavior(deltaTime float64, spawnerPipeline chan shared_structs.HasBehavior) {
-	if b.expirationDate.UnixMilli() < time.Now().UnixMilli() {
+func (b *Bullet) ApplyBehavior(tick shared_structs.Tick, spawnerPipeline chan shared_structs.HasBehavior) {
+	b.lifetime -= tick.DeltaTime
+	if b.lifetime <= 0 {
 		b.GameObject.Delete = true
 	}
-	b.Body.EachArbiter(func(arbiter *cp.Arbiter) {
-		_, bodB := arbiter.Bodies()
-		if ptr, ok := bodB.UserData.(*shared_structs.GameObject); ok {
-			if ptr.Identity == constants.Player || ptr.Identity == constants.Turret || ptr.Identity == constants.Bullet {
-				b.Delete = true
-			}
+}
+
+// Stopped is the collision rule for a bullet hitting something that stops it, like a player or another bullet.
+// Bullets bounce off walls.
+func Stopped(c collision.Contact) {
+	c.A.Delete = true
+}
+
+// A bullet spawned by name is fired from its owner, wherever that is pointing. X and Y aren't used.
+func init() {
+	entity.Requires("shrapnel", true)
+	entity.RequiresPositive(string(constants.Bullet), "speed", "lifetime")
+	entity.RequiresPositive("shrapnel", "speed", "lifetime")
+	entity.Register(constants.Bullet, func(params entity.Params) (shared_structs.HasBehavior, error) {
+		if params.Owner == nil || params.Owner.Body == nil {
+			return nil, errors.New("it needs an owner to fire it")
 		}
+		return NewBullet(params.Owner), nil
 	})
 }
@@ -0,0 +1,77 @@
+package clock
+
+import (
+	"Geomyidae/internal/shared_structs"
+	"log"
+	"time"
+)
+
+// Clock turns wall clock time into a whole number of fixed length simulation ticks.
+// The simulation only ever advances by DeltaTime, so a loaded server runs the same
+// simulation as an idle one, it just runs it in bursts.
+type Clock struct {
+	// TickRate is how many ticks are simulated per second
+	TickRate int
+	// MaxCatchUp is the most ticks that will be simulated back to back before giving up on the backlog.
+	// Without a cap, a server that falls behind spends longer catching up, which makes it fall further behind.
+	MaxCatchUp int
+
+	tick        uint64
+	accumulator time.Duration
+	last        time.Time
+}
+
+func New(tickRate, maxCatchUp int) *Clock {
+	if tickRate <= 0 {
+		log.Fatal("tick rate must be positive")
+	}
+	if maxCatchUp <= 0 {
+		maxCatchUp = 1
+	}
+	return &Clock{TickRate: tickRate, MaxCatchUp: maxCatchUp, last: time.Now()}
+}
+
+// DeltaTime is the length of a tick in seconds
+func (c *Clock) DeltaTime() float64 {
+	return 1 / float64(c.TickRate)
+}
+
+func (c *Clock) interval() time.Duration {
+	return time.Second / time.Duration(c.TickRate)
+}
+
+// Steps returns how many ticks are due since the last call.
+// If more than MaxCatchUp ticks are due, the rest are dropped and the simulation slows down instead.
+func (c *Clock) Steps() int {
+	now := time.Now()
+	c.accumulator += now.Sub(c.last)
+	c.last = now
+
+	steps := int(c.accumulator / c.interval())
+	if steps > c.MaxCatchUp {
+		log.Printf("simulation is %d ticks behind, dropping %d", steps, steps-c.MaxCatchUp)
+		c.accumulator = 0
+		return c.MaxCatchUp
+	}
+	c.accumulator -= time.Duration(steps) * c.interval()
+	return steps
+}
+
+// Next advances the tick counter and returns the tick to simulate
+func (c *Clock) Next() shared_structs.Tick {
+	c.tick++
+	return shared_structs.Tick{Number: c.tick, DeltaTime: c.DeltaTime()}
+}
+
+// Current is the number of the most recently simulated tick
+func (c *Clock) Current() uint64 {
+	return c.tick
+}
+
+// Wait sleeps until the next tick is due
+func (c *Clock) Wait() {
+	remaining := c.interval() - c.accumulator - time.Since(c.last)
+	if remaining > 0 {
+		time.Sleep(remaining)
+	}
+}
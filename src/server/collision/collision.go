@@ -0,0 +1,138 @@
+package collision
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/combat"
+	"fmt"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// What happens when two objects touch is decided by Rules, one for each pair of identities that interact, instead of every
+// object going through its arbiters each tick. The rules are run by chipmunk's collision handlers during the physics step,
+// so a contact is never missed, even one that starts and ends within a single step, and each side of it is seen.
+//
+// Every identity that is the A side of a rule gets its own collision type, and a wildcard handler that runs the rules for
+// whatever it touched. The B side can be anything, including tiles, whose shapes keep the collision type they have.
+// Rules don't change whether the two bounce off each other: handlers like tile.HandleOneWay do that.
+
+// Contact is two objects touching. A has the first identity of the rule, B the second.
+type Contact struct {
+	Arbiter *cp.Arbiter
+	A       *shared_structs.GameObject
+	B       *shared_structs.GameObject
+	// EntityA and EntityB are the simulation objects A and B belong to, if they were attached with Attach. Walls have none.
+	EntityA shared_structs.HasBehavior
+	EntityB shared_structs.HasBehavior
+	// Tick is the tick being stepped, and Spawn the spawnerPipeline, for anything the contact creates
+	Tick  shared_structs.Tick
+	Spawn chan shared_structs.HasBehavior
+	// Rules are who can hurt who, as given to Install
+	Rules combat.Rules
+}
+
+// Rule is what happens when an object of identity A touches one of identity B.
+// Begin runs when they first touch, PreSolve every step they touch, including the first, and Separate when they part.
+// Any of them can be nil.
+type Rule struct {
+	A        constants.UserDataCode
+	B        constants.UserDataCode
+	Begin    func(c Contact)
+	PreSolve func(c Contact)
+	Separate func(c Contact)
+}
+
+// pair is who touched whom, from the side of the wildcard handler running
+type pair struct {
+	a constants.UserDataCode
+	b constants.UserDataCode
+}
+
+// firstType is the first collision type handed out. The ones below it are for handlers installed elsewhere,
+// like tile.OneWayCollisionType.
+const firstType cp.CollisionType = 16
+
+var (
+	types = make(map[constants.UserDataCode]cp.CollisionType)
+	rules = make(map[pair][]Rule)
+	// combatRules are passed on to every contact
+	combatRules combat.Rules
+	// the tick being stepped, see Step
+	tick  shared_structs.Tick
+	spawn chan shared_structs.HasBehavior
+)
+
+// Install adds a handler to space for every identity on the A side of a rule. hurting decides who can hurt who.
+// It is called once, before anything is attached.
+func Install(space *cp.Space, table []Rule, hurting combat.Rules) {
+	combatRules = hurting
+	for _, rule := range table {
+		if rule.Begin == nil && rule.PreSolve == nil && rule.Separate == nil {
+			panic(fmt.Sprintf("collision: the rule for %v and %v does nothing", rule.A, rule.B))
+		}
+		key := pair{rule.A, rule.B}
+		rules[key] = append(rules[key], rule)
+		if _, ok := types[rule.A]; ok {
+			continue
+		}
+		code := rule.A
+		types[code] = firstType + cp.CollisionType(len(types))
+		handler := space.NewWildcardCollisionHandler(types[code])
+		handler.BeginFunc = func(arb *cp.Arbiter, space *cp.Space, userData interface{}) bool {
+			run(arb, code, func(rule Rule) func(Contact) { return rule.Begin })
+			return true
+		}
+		handler.PreSolveFunc = func(arb *cp.Arbiter, space *cp.Space, userData interface{}) bool {
+			run(arb, code, func(rule Rule) func(Contact) { return rule.PreSolve })
+			return true
+		}
+		handler.SeparateFunc = func(arb *cp.Arbiter, space *cp.Space, userData interface{}) {
+			run(arb, code, func(rule Rule) func(Contact) { return rule.Separate })
+		}
+	}
+}
+
+// run runs the callbacks of every rule for what the shape of identity code touched
+func run(arb *cp.Arbiter, code constants.UserDataCode, callback func(rule Rule) func(Contact)) {
+	// in a wildcard handler the handler's own shape always comes first
+	shapeA, shapeB := arb.Shapes()
+	a, entityA := objectOf(shapeA)
+	b, entityB := objectOf(shapeB)
+	if a == nil || b == nil || a.Identity != code {
+		return
+	}
+	for _, rule := range rules[pair{code, b.Identity}] {
+		if fn := callback(rule); fn != nil {
+			fn(Contact{Arbiter: arb, A: a, B: b, EntityA: entityA, EntityB: entityB, Tick: tick, Spawn: spawn, Rules: combatRules})
+		}
+	}
+}
+
+// objectOf finds the game object a shape belongs to, and the simulation object too if it was attached
+func objectOf(shape *cp.Shape) (*shared_structs.GameObject, shared_structs.HasBehavior) {
+	if entity, ok := shape.UserData.(shared_structs.HasBehavior); ok {
+		return entity.GetObject(), entity
+	}
+	obj, _ := shape.Body().UserData.(*shared_structs.GameObject)
+	return obj, nil
+}
+
+// Attach gives the shape of obj the collision type of its identity, and lets rules find obj from it.
+// Everything is attached as it is added to the world.
+func Attach(obj shared_structs.HasBehavior) {
+	gameObj := obj.GetObject()
+	if gameObj.Shape == nil {
+		return
+	}
+	gameObj.Shape.UserData = obj
+	if collisionType, ok := types[gameObj.Identity]; ok {
+		gameObj.Shape.SetCollisionType(collisionType)
+	}
+}
+
+// Step steps space by one tick, running the rules for every contact in it
+func Step(space *cp.Space, current shared_structs.Tick, spawnerPipeline chan shared_structs.HasBehavior) {
+	tick, spawn = current, spawnerPipeline
+	space.Step(current.DeltaTime)
+}
@@ -0,0 +1,178 @@
+package collision
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/combat"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// Install can only be called once, so every test shares one space with the rules below in it
+
+var (
+	space   *cp.Space
+	hurting = combat.Rules{FriendlyFire: true}
+	// contacts are what the rules saw, like "player-bullet begin alice bang"
+	contacts []string
+)
+
+func TestMain(m *testing.M) {
+	space = cp.NewSpace()
+	Install(space, []Rule{
+		{A: constants.Player, B: constants.Bullet, Begin: record("player-bullet begin"), PreSolve: record("player-bullet presolve"), Separate: record("player-bullet separate")},
+		{A: constants.Bullet, B: constants.Player, Begin: record("bullet-player begin")},
+		{A: constants.Player, B: constants.Tile, Begin: record("player-tile begin")},
+		{A: constants.Player, B: constants.Tile, Begin: record("player-tile begin again")},
+		{A: constants.Turret, B: constants.Bullet, Begin: record("turret-bullet begin")},
+	}, hurting)
+	os.Exit(m.Run())
+}
+
+// thing is a simulation object that does nothing
+type thing struct {
+	obj *shared_structs.GameObject
+}
+
+func (t *thing) ApplyBehavior(tick shared_structs.Tick, spawnerPipeline chan shared_structs.HasBehavior) {
+}
+
+func (t *thing) GetObject() *shared_structs.GameObject {
+	return t.obj
+}
+
+// record returns a callback adding what it saw to contacts
+func record(rule string) func(c Contact) {
+	return func(c Contact) {
+		if c.EntityA == nil || c.EntityA.GetObject() != c.A {
+			panic(fmt.Sprintf("%v: A wasn't attached", rule))
+		}
+		if c.Rules != hurting || c.Tick.Number == 0 {
+			panic(fmt.Sprintf("%v: got the rules %+v and the tick %+v", rule, c.Rules, c.Tick))
+		}
+		contacts = append(contacts, fmt.Sprintf("%v %v %v", rule, c.A.UUID, c.B.UUID))
+	}
+}
+
+// add attaches a ball of identity code at x, 0
+func add(code constants.UserDataCode, name string, x float64) *thing {
+	body := space.AddBody(cp.NewBody(1, cp.MomentForCircle(1, 0, 0.5, cp.Vector{})))
+	body.SetPosition(cp.Vector{X: x})
+	shape := space.AddShape(cp.NewCircle(body, 0.5, cp.Vector{}))
+	obj := &thing{&shared_structs.GameObject{UUID: name, Identity: code, Body: body, Shape: shape}}
+	Attach(obj)
+	return obj
+}
+
+// addWall adds a box at x, 0 the way tiles are added, with the game object on the body and nothing attached
+func addWall(name string, x float64) *cp.Shape {
+	body := space.AddBody(cp.NewStaticBody())
+	body.SetPosition(cp.Vector{X: x})
+	body.UserData = &shared_structs.GameObject{UUID: name, Identity: constants.Tile, Body: body}
+	return space.AddShape(cp.NewBox(body, 1, 1, 0))
+}
+
+// step steps the space once, and returns what the rules saw, sorted because the order of arbiters isn't defined
+func step(number uint64) []string {
+	contacts = nil
+	Step(space, shared_structs.Tick{Number: number, DeltaTime: 1.0 / 50}, nil)
+	slices.Sort(contacts)
+	return contacts
+}
+
+// remove takes everything out of the space
+func remove() {
+	var shapes []*cp.Shape
+	space.EachShape(func(shape *cp.Shape) { shapes = append(shapes, shape) })
+	for _, shape := range shapes {
+		space.RemoveShape(shape)
+	}
+	var bodies []*cp.Body
+	space.EachBody(func(body *cp.Body) { bodies = append(bodies, body) })
+	for _, body := range bodies {
+		if body != space.StaticBody {
+			space.RemoveBody(body)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func()
+		want  []string
+	}{
+		{
+			// both sides have a rule for the other, so both run, each with its own identity as A
+			name:  "both ways",
+			setup: func() { add(constants.Player, "alice", 0); add(constants.Bullet, "bang", 0.5) },
+			want:  []string{"bullet-player begin bang alice", "player-bullet begin alice bang", "player-bullet presolve alice bang"},
+		},
+		{
+			// the shapes come the other way round, the rules don't
+			name:  "added the other way round",
+			setup: func() { add(constants.Bullet, "bang", 0); add(constants.Player, "alice", 0.5) },
+			want:  []string{"bullet-player begin bang alice", "player-bullet begin alice bang", "player-bullet presolve alice bang"},
+		},
+		{
+			name:  "wall",
+			setup: func() { add(constants.Player, "alice", 0); addWall("wall", 0.5) },
+			want:  []string{"player-tile begin again alice wall", "player-tile begin alice wall"},
+		},
+		{
+			// a turret has rules, but none for players, and players have none for turrets
+			name:  "no rule",
+			setup: func() { add(constants.Player, "alice", 0); add(constants.Turret, "gun", 0.5) },
+		},
+		{
+			name:  "neither has rules",
+			setup: func() { add(constants.Pickup, "bombplus", 0); add(constants.Tracker, "chaser", 0.5) },
+		},
+		{
+			name: "nothing attached",
+			setup: func() {
+				add(constants.Player, "alice", 0)
+				// a shape with no game object, on the body or attached
+				space.AddShape(cp.NewCircle(space.StaticBody, 0.5, cp.Vector{X: 0.5}))
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer remove()
+			test.setup()
+			got := step(2)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// TestSeparate checks PreSolve runs every step the two touch, and Separate once when they part
+func TestSeparate(t *testing.T) {
+	defer remove()
+	alice := add(constants.Player, "alice", 0)
+	bang := add(constants.Bullet, "bang", 0.5)
+	step(2)
+	alice.obj.Body.SetVelocity(0, 0)
+	bang.obj.Body.SetVelocity(0, 0)
+	bang.obj.Body.SetPosition(cp.Vector{X: 0.9})
+	got := step(3)
+	if want := []string{"player-bullet presolve alice bang"}; !slices.Equal(got, want) {
+		t.Errorf("touching again got %q, want %q", got, want)
+	}
+	bang.obj.Body.SetPosition(cp.Vector{X: 10})
+	got = step(4)
+	if want := []string{"player-bullet separate alice bang"}; !slices.Equal(got, want) {
+		t.Errorf("parting got %q, want %q", got, want)
+	}
+	got = step(5)
+	if len(got) != 0 {
+		t.Errorf("apart got %q", got)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/server/bomb"
+	"Geomyidae/server/bullet"
+	"Geomyidae/server/collision"
+	"Geomyidae/server/pickup"
+	"Geomyidae/server/player"
+	"Geomyidae/server/tile"
+	"Geomyidae/server/tracker"
+	"Geomyidae/server/turret"
+)
+
+// collisions are what happens when things touch, see the collision package.
+// Anything that isn't listed just bounces off whatever it hits.
+var collisions = []collision.Rule{
+	// ships are hurt by anything with damage, and set off triggers
+	{A: constants.Player, B: constants.Bullet, PreSolve: player.Hurt},
+	{A: constants.Player, B: constants.Shrapnel, PreSolve: player.Hurt},
+	{A: constants.Player, B: constants.Tracker, PreSolve: player.Hurt},
+	{A: constants.Player, B: constants.Tile, PreSolve: player.Hurt},
+	{A: constants.Player, B: constants.Tile, PreSolve: tile.Triggered},
+
+	{A: constants.Pickup, B: constants.Player, Begin: pickup.Collected},
+
+	{A: constants.Turret, B: constants.Bullet, Begin: turret.Shot},
+	{A: constants.Turret, B: constants.Shrapnel, Begin: turret.Shot},
+	{A: constants.Tracker, B: constants.Bullet, Begin: tracker.Shot},
+	{A: constants.Tracker, B: constants.Shrapnel, Begin: tracker.Shot},
+	{A: constants.Tracker, B: constants.Player, Begin: tracker.Crashed},
+	{A: constants.Bomb, B: constants.Bullet, Begin: bomb.Shot},
+	{A: constants.Bomb, B: constants.Shrapnel, Begin: bomb.Shot},
+
+	// shrapnel is stopped by the same things as bullets
+	{A: constants.Bullet, B: constants.Player, Begin: bullet.Stopped},
+	{A: constants.Bullet, B: constants.Turret, Begin: bullet.Stopped},
+	{A: constants.Bullet, B: constants.Bullet, Begin: bullet.Stopped},
+	{A: constants.Bullet, B: constants.Shrapnel, Begin: bullet.Stopped},
+	{A: constants.Shrapnel, B: constants.Player, Begin: bullet.Stopped},
+	{A: constants.Shrapnel, B: constants.Turret, Begin: bullet.Stopped},
+	{A: constants.Shrapnel, B: constants.Bullet, Begin: bullet.Stopped},
+	{A: constants.Shrapnel, B: constants.Shrapnel, Begin: bullet.Stopped},
+}
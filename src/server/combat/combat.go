@@ -0,0 +1,42 @@
+package combat
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+)
+
+// Everything that can hurt something else has an owner and a team.
+// A bullet's owner is whoever fired it, and it is on their team. Objects that do damage themselves, like trackers, own themselves.
+
+// Rules decide who can hurt who. The server sets them from the command line and hands them to the collision rules.
+type Rules struct {
+	// FriendlyFire lets objects hurt others on the same team
+	FriendlyFire bool
+	// SelfDamage lets players hurt themselves, with their own bomb shrapnel for example
+	SelfDamage bool
+}
+
+// Teams that ship with the game. Players are all on one team against the enemies, unless friendly fire is on.
+const (
+	TeamPlayers constants.Team = "players"
+	TeamEnemies constants.Team = "enemies"
+)
+
+// OwnerOf returns the UUID of whoever gets the credit for what attacker does
+func OwnerOf(attacker *shared_structs.GameObject) string {
+	if attacker.Owner != "" {
+		return attacker.Owner
+	}
+	return attacker.UUID
+}
+
+// CanHurt reports whether attacker is allowed to hurt target under the rules
+func (r Rules) CanHurt(attacker, target *shared_structs.GameObject) bool {
+	if OwnerOf(attacker) == target.UUID {
+		return r.SelfDamage
+	}
+	if attacker.Team != "" && attacker.Team == target.Team {
+		return r.FriendlyFire
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package combat
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+)
+
+// AssistWindow is how recently, in seconds, someone has to have hurt a victim to get an assist for its death
+const AssistWindow = 10.0
+
+// Kill is emitted whenever something is destroyed by an attack
+type Kill struct {
+	Tick       uint64
+	Victim     string // UUID
+	VictimName string
+	VictimType constants.UserDataCode
+	// Killer is the owner of whatever landed the final hit. It is the same as Victim if they killed themselves.
+	Killer string
+	// Assists are the owners of everything else that hurt the victim within AssistWindow seconds
+	Assists []string
+}
+
+// kills and hits are what was reported since the last Drain. Like everything else in the simulation, they are only
+// touched with the player List's WriteAccess held.
+var (
+	kills []Kill
+	hits  []Hit
+)
+
+// Report emits a kill
+func Report(kill Kill) {
+	kills = append(kills, kill)
+}
+
+// Hit is emitted whenever an attack lands
+type Hit struct {
+	Tick     uint64
+	Attacker string                 // owner of what landed the hit
+	Weapon   constants.UserDataCode // what landed it, like a bullet or shrapnel
+	Victim   string                 // UUID
+	Damage   int
+}
+
+// ReportHit emits a hit by attacker on victim
+func ReportHit(attacker, victim *shared_structs.GameObject, tick shared_structs.Tick) {
+	hits = append(hits, Hit{Tick: tick.Number, Attacker: OwnerOf(attacker), Weapon: attacker.Identity, Victim: victim.UUID, Damage: attacker.Damage})
+}
+
+// Drain returns every kill and hit reported since it was last called, oldest first, and forgets them.
+// The main loop calls it after every tick. Nothing is ever dropped.
+func Drain() ([]Kill, []Hit) {
+	drained, drainedHits := kills, hits
+	kills, hits = nil, nil
+	return drained, drainedHits
+}
+
+// Attackers remembers who has hurt something recently, so they can share the credit when it dies.
+// The zero value is ready to use.
+type Attackers struct {
+	lastHit map[string]uint64 // tick of each owner's last hit
+}
+
+// Hit records that attacker hurt the victim this tick
+func (a *Attackers) Hit(attacker *shared_structs.GameObject, tick shared_structs.Tick) {
+	if a.lastHit == nil {
+		a.lastHit = make(map[string]uint64)
+	}
+	a.lastHit[OwnerOf(attacker)] = tick.Number
+}
+
+// Killed reports the victim's death at the hands of attacker, with everyone else who hurt it recently as assists,
+// and forgets everyone so the next life starts clean
+func (a *Attackers) Killed(victim, attacker *shared_structs.GameObject, tick shared_structs.Tick) {
+	killer := OwnerOf(attacker)
+	kill := Kill{Tick: tick.Number, Victim: victim.UUID, VictimName: victim.Name, VictimType: victim.Identity, Killer: killer}
+	window := uint64(AssistWindow / tick.DeltaTime)
+	for owner, last := range a.lastHit {
+		if owner != killer && owner != victim.UUID && last+window >= tick.Number {
+			kill.Assists = append(kill.Assists, owner)
+		}
+	}
+	clear(a.lastHit)
+	Report(kill)
+}
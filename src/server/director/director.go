@@ -0,0 +1,229 @@
+package director
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/entity"
+	"cmp"
+	"log"
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// The director spawns enemies a wave at a time, following a Plan.
+// How many enemies a wave has depends on how many players there are, how long the round has gone on,
+// and how the players did against the last wave.
+
+// Director runs a Plan. It is driven by the game mode every tick.
+type Director struct {
+	plan   *Plan
+	spawns []cp.Vector // where enemies appear, in meters
+
+	wave      int     // the current wave, counting from 1. 0 before the first wave.
+	repeats   int     // how many times the last wave has been replayed, in endless plans
+	intensity float64 // performance adjustment, 1 is as written
+	waiting   float64 // seconds until the next wave starts
+	elapsed   float64 // seconds since Reset
+
+	pending     []order                      // enemies in the current wave that haven't spawned yet, soonest first
+	enemies     []*shared_structs.GameObject // enemies in the current wave that haven't been killed
+	killed      []string                     // enemies killed since the last Update
+	targets     []*shared_structs.GameObject // who enemies could go after at the last Update
+	waveStarted float64                      // elapsed when the current wave started
+	deaths      int                          // player deaths during the current wave
+}
+
+// order is an enemy waiting to spawn
+type order struct {
+	at   float64 // elapsed time to spawn at
+	kind constants.UserDataCode
+}
+
+// New returns a director for plan, spawning enemies at random out of spawns, which can't be empty
+func New(plan *Plan, spawns []cp.Vector) *Director {
+	d := &Director{plan: plan, spawns: spawns}
+	d.Reset()
+	return d
+}
+
+// Reset removes any enemies still around and starts over from the first wave.
+// Those don't count as destroyed, a wave is only cleared by killing everything in it.
+func (d *Director) Reset() {
+	for _, enemy := range d.enemies {
+		enemy.Delete = true
+	}
+	d.enemies = nil
+	d.killed = nil
+	d.pending = nil
+	d.wave = 0
+	d.repeats = 0
+	d.intensity = 1
+	d.elapsed = 0
+	d.deaths = 0
+	d.waiting = d.plan.Waves[0].Break
+}
+
+// Wave is the current wave, counting from 1
+func (d *Director) Wave() int {
+	return d.wave
+}
+
+// Cleared is how many waves have been cleared
+func (d *Director) Cleared() int {
+	if d.inWave() {
+		return d.wave - 1
+	}
+	return d.wave
+}
+
+// Done reports whether every wave has been cleared. Endless plans are never done.
+func (d *Director) Done() bool {
+	return !d.plan.Endless && d.wave >= len(d.plan.Waves) && !d.inWave()
+}
+
+// PlayerDied lets the director know a player died, so it can go easier on them
+func (d *Director) PlayerDied() {
+	d.deaths++
+}
+
+// Killed lets the director know something was killed. Only killed enemies count towards clearing a wave.
+func (d *Director) Killed(victim string) {
+	if slices.ContainsFunc(d.enemies, func(enemy *shared_structs.GameObject) bool { return enemy.UUID == victim }) {
+		d.killed = append(d.killed, victim)
+	}
+}
+
+func (d *Director) inWave() bool {
+	return len(d.pending) > 0 || len(d.enemies) > 0
+}
+
+// Update spawns whatever is due this tick. targets are the players enemies can be sent after.
+func (d *Director) Update(tick shared_structs.Tick, targets []*shared_structs.GameObject, spawn func(obj shared_structs.HasBehavior)) {
+	d.elapsed += tick.DeltaTime
+	d.targets = targets
+	if len(targets) == 0 {
+		// nobody to send enemies after, hold everything until somebody shows up
+		return
+	}
+
+	if d.inWave() {
+		// the wave is only cleared here, so the last enemy going has to be noticed in the same place.
+		// Enemies that went without being killed, like off the edge of the map, come back.
+		d.enemies = slices.DeleteFunc(d.enemies, func(enemy *shared_structs.GameObject) bool {
+			if enemy.Delete && !slices.Contains(d.killed, enemy.UUID) {
+				d.pending = slices.Insert(d.pending, 0, order{at: d.elapsed, kind: enemy.Identity})
+			}
+			return enemy.Delete
+		})
+		d.killed = nil
+		for len(d.pending) > 0 && d.pending[0].at <= d.elapsed {
+			d.spawn(d.pending[0].kind, targets, spawn)
+			d.pending = d.pending[1:]
+		}
+		if !d.inWave() {
+			d.waveCleared(len(targets))
+		}
+		return
+	}
+	if d.Done() {
+		return
+	}
+	d.waiting -= tick.DeltaTime
+	if d.waiting <= 0 {
+		d.startWave(len(targets))
+	}
+}
+
+// waveCleared adjusts the intensity depending on how the players did, and starts the break before the next wave
+func (d *Director) waveCleared(players int) {
+	took := d.elapsed - d.waveStarted
+	step := d.plan.PerformanceStep
+	switch {
+	case d.deaths >= players:
+		d.intensity -= step
+	case d.deaths == 0 && took < d.plan.FastClear:
+		d.intensity += step
+	}
+	d.intensity = min(max(d.intensity, d.plan.MinIntensity), d.plan.MaxIntensity)
+	log.Printf("wave %d cleared in %.0f seconds with %d deaths, intensity is now %.2f", d.wave, took, d.deaths, d.intensity)
+	if next := d.nextWave(); next != nil {
+		d.waiting = next.Break
+	}
+}
+
+// nextWave is the wave that comes after the current one, or nil if there isn't one
+func (d *Director) nextWave() *Wave {
+	if d.wave < len(d.plan.Waves) {
+		return &d.plan.Waves[d.wave]
+	}
+	if d.plan.Endless {
+		return &d.plan.Waves[len(d.plan.Waves)-1]
+	}
+	return nil
+}
+
+func (d *Director) startWave(players int) {
+	wave := d.nextWave()
+	if d.wave >= len(d.plan.Waves) {
+		// replaying the last wave of an endless plan, each time it gets harder
+		d.repeats++
+	}
+	d.wave++
+	d.waveStarted = d.elapsed
+	d.deaths = 0
+
+	scale := d.intensity * (1 + d.plan.TimeScale*d.elapsed/60) * (1 + float64(d.repeats)*d.plan.PerformanceStep)
+	d.pending = nil
+	for _, group := range wave.Enemies {
+		count := d.count(group, players, scale)
+		for i := range count {
+			d.pending = append(d.pending, order{at: d.elapsed + float64(i)*group.Interval, kind: group.Type})
+		}
+	}
+	slices.SortStableFunc(d.pending, func(a, b order) int {
+		return cmp.Compare(a.at, b.at)
+	})
+	log.Printf("wave %d, %v: %d enemies for %d players", d.wave, wave.Name, len(d.pending), players)
+	if len(d.pending) == 0 {
+		// nothing to fight, it's cleared already
+		d.waveCleared(players)
+	}
+}
+
+// count is how many of group to spawn
+func (d *Director) count(group Group, players int, scale float64) int {
+	base := float64(group.Count) + group.PerPlayer*float64(players-1)
+	if base <= 0 {
+		return 0
+	}
+	return max(1, int(math.Round(base*scale)))
+}
+
+func (d *Director) spawn(kind constants.UserDataCode, targets []*shared_structs.GameObject, spawn func(obj shared_structs.HasBehavior)) {
+	target := targets[rand.IntN(len(targets))]
+	point := d.spawns[rand.IntN(len(d.spawns))]
+	enemy, err := entity.New(kind, entity.Params{X: point.X, Y: point.Y, Target: target, Retarget: d.retarget})
+	if err != nil {
+		log.Printf("director: %v", err)
+		return
+	}
+	spawn(enemy)
+	d.enemies = append(d.enemies, enemy.GetObject())
+}
+
+// retarget picks someone for an enemy to go after once its target has left, or nil if everybody has
+func (d *Director) retarget() *shared_structs.GameObject {
+	var targets []*shared_structs.GameObject
+	for _, target := range d.targets {
+		if !target.Delete {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	return targets[rand.IntN(len(targets))]
+}
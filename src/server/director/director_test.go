@@ -0,0 +1,331 @@
+package director
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/entity"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jakecoffman/cp/v2"
+)
+
+// drones are enemies that do nothing, so the director can be tested without the real ones
+const drone constants.UserDataCode = "drone"
+
+type droneObj struct {
+	obj      *shared_structs.GameObject
+	target   *shared_structs.GameObject
+	retarget func() *shared_structs.GameObject
+	point    cp.Vector
+	at       float64 // seconds into the game it spawned
+}
+
+func (d *droneObj) ApplyBehavior(tick shared_structs.Tick, spawnerPipeline chan shared_structs.HasBehavior) {
+}
+
+func (d *droneObj) GetObject() *shared_structs.GameObject {
+	return d.obj
+}
+
+func init() {
+	entity.Register(drone, func(params entity.Params) (shared_structs.HasBehavior, error) {
+		return &droneObj{
+			obj:      &shared_structs.GameObject{UUID: uuid.New().String(), Identity: drone},
+			target:   params.Target,
+			retarget: params.Retarget,
+			point:    cp.Vector{X: params.X, Y: params.Y},
+		}, nil
+	})
+}
+
+// game drives a director the way a game mode does, a quarter of a second a tick
+type game struct {
+	t        *testing.T
+	director *Director
+	players  []*shared_structs.GameObject
+	elapsed  float64
+	spawned  []*droneObj
+}
+
+func newGame(t *testing.T, plan string, players int) *game {
+	t.Helper()
+	loaded, err := Load([]byte(plan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &game{t: t, director: New(loaded, []cp.Vector{{X: 1, Y: 2}})}
+	for range players {
+		g.players = append(g.players, &shared_structs.GameObject{Identity: constants.Player})
+	}
+	return g
+}
+
+// run plays for seconds, and returns when the drones spawned in that time did
+func (g *game) run(seconds float64) []float64 {
+	var at []float64
+	for end := g.elapsed + seconds; g.elapsed < end; {
+		g.elapsed += 0.25
+		g.director.Update(shared_structs.Tick{DeltaTime: 0.25}, g.players, func(obj shared_structs.HasBehavior) {
+			d := obj.(*droneObj)
+			d.at = g.elapsed
+			g.spawned = append(g.spawned, d)
+			at = append(at, g.elapsed)
+		})
+	}
+	return at
+}
+
+// destroy kills d, the way a bullet does
+func (g *game) destroy(d *droneObj) {
+	d.obj.Delete = true
+	g.director.Killed(d.obj.UUID)
+}
+
+// kill destroys every drone spawned so far
+func (g *game) kill() {
+	for _, d := range g.spawned {
+		g.destroy(d)
+	}
+}
+
+// clear kills everything in the current wave as soon as it has all spawned, and returns how many there were
+func (g *game) clear() int {
+	g.t.Helper()
+	before := len(g.spawned)
+	for range 400 {
+		if len(g.director.pending) == 0 {
+			break
+		}
+		g.run(0.25)
+	}
+	if len(g.director.pending) > 0 {
+		g.t.Fatalf("wave %d is still spawning", g.director.Wave())
+	}
+	g.kill()
+	g.run(0.25)
+	return len(g.spawned) - before
+}
+
+const twoWaves = `{
+	"waves": [
+		{"name": "first", "break": 2, "enemies": [{"type": "drone", "count": 3, "interval": 1}]},
+		{"name": "second", "break": 5, "enemies": [{"type": "drone", "count": 1}]}
+	]
+}`
+
+func TestPacing(t *testing.T) {
+	g := newGame(t, twoWaves, 1)
+
+	if at := g.run(1.75); len(at) != 0 || g.director.Wave() != 0 {
+		t.Fatalf("during the first break, %v spawned in wave %d", at, g.director.Wave())
+	}
+	at := g.run(5.25)
+	if want := []float64{2.25, 3, 4}; !slices.Equal(at, want) {
+		t.Errorf("the first wave spawned at %v, want %v", at, want)
+	}
+	if g.director.Wave() != 1 || g.director.Cleared() != 0 {
+		t.Errorf("during the first wave it's wave %d with %d cleared", g.director.Wave(), g.director.Cleared())
+	}
+	for _, d := range g.spawned {
+		if d.target != g.players[0] || d.point != (cp.Vector{X: 1, Y: 2}) {
+			t.Errorf("a drone at %v is going after %p, not the player at the spawn point", d.point, d.target)
+		}
+	}
+
+	// it isn't cleared while anything is still around
+	g.destroy(g.spawned[0])
+	g.destroy(g.spawned[1])
+	if g.run(10); g.director.Cleared() != 0 {
+		t.Error("the first wave was cleared with a drone left")
+	}
+	g.kill()
+	g.run(0.25)
+	if g.director.Cleared() != 1 || g.director.Done() {
+		t.Errorf("after the first wave %d are cleared and done is %v", g.director.Cleared(), g.director.Done())
+	}
+
+	// then the second wave's break
+	if at := g.run(4.75); len(at) != 0 {
+		t.Errorf("during the second break %v spawned", at)
+	}
+	if at := g.run(0.5); len(at) != 1 || g.director.Wave() != 2 {
+		t.Errorf("the second wave spawned %v in wave %d", at, g.director.Wave())
+	}
+	g.kill()
+	g.run(0.25)
+	if !g.director.Done() || g.director.Cleared() != 2 {
+		t.Errorf("after the last wave %d are cleared and done is %v", g.director.Cleared(), g.director.Done())
+	}
+	if at := g.run(60); len(at) != 0 {
+		t.Errorf("after the last wave %v spawned", at)
+	}
+}
+
+// TestNoPlayers checks the director holds everything while there is nobody to go after
+func TestNoPlayers(t *testing.T) {
+	g := newGame(t, twoWaves, 1)
+	players := g.players
+	g.players = nil
+	if at := g.run(30); len(at) != 0 || g.director.Wave() != 0 {
+		t.Fatalf("with nobody playing, %v spawned in wave %d", at, g.director.Wave())
+	}
+	g.players = players
+	if at := g.run(2.25); len(at) != 1 {
+		t.Errorf("once somebody joined, %v spawned", at)
+	}
+	g.players = nil
+	if at := g.run(30); len(at) != 0 {
+		t.Errorf("after everybody left, %v spawned", at)
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		group   Group
+		players int
+		scale   float64
+		want    int
+	}{
+		{"as written", Group{Count: 3}, 1, 1, 3},
+		{"more players", Group{Count: 3, PerPlayer: 1.5}, 3, 1, 6},
+		{"only with more players", Group{PerPlayer: 0.5}, 1, 1, 0},
+		{"scaled", Group{Count: 4}, 1, 1.5, 6},
+		{"scaled right down", Group{Count: 1}, 1, 0.1, 1},
+		{"none", Group{}, 4, 2, 0},
+	}
+	d := &Director{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := d.count(test.group, test.players, test.scale); got != test.want {
+				t.Errorf("count(%+v, %d, %v) = %d, want %d", test.group, test.players, test.scale, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPerPlayer(t *testing.T) {
+	g := newGame(t, `{
+		"waves": [{"break": 1, "enemies": [{"type": "drone", "count": 2, "per_player": 1}, {"type": "drone", "per_player": 2}]}]
+	}`, 3)
+	g.run(1)
+	if got := g.clear(); got != 4+4 {
+		t.Errorf("3 players got %d drones, want 8", got)
+	}
+}
+
+// TestIntensity checks a wave cleared quickly makes the next one harder, and everyone dying makes it easier
+func TestIntensity(t *testing.T) {
+	g := newGame(t, `{
+		"waves": [{"break": 1, "enemies": [{"type": "drone", "count": 4}]}],
+		"endless": true,
+		"fast_clear": 10,
+		"performance_step": 0.5,
+		"min_intensity": 0.5,
+		"max_intensity": 1.5
+	}`, 2)
+	wave := func() int {
+		t.Helper()
+		g.run(1)
+		return g.clear()
+	}
+
+	if got := wave(); got != 4 {
+		t.Errorf("the first wave has %d", got)
+	}
+	if g.director.intensity != 1.5 {
+		t.Errorf("after a fast clear the intensity is %v", g.director.intensity)
+	}
+	// replays of an endless plan are performance_step harder each time too
+	if got := wave(); got != 9 { // 4 * 1.5 * 1.5
+		t.Errorf("the wave after a fast clear has %d", got)
+	}
+	if g.director.intensity != 1.5 {
+		t.Errorf("the intensity went past the max to %v", g.director.intensity)
+	}
+
+	g.run(1)
+	g.director.PlayerDied()
+	if g.clear(); g.director.intensity != 1.5 {
+		t.Errorf("with one of two players dying the intensity is %v", g.director.intensity)
+	}
+	g.run(1)
+	g.director.PlayerDied()
+	g.director.PlayerDied()
+	if g.clear(); g.director.intensity != 1 {
+		t.Errorf("with both players dying the intensity is %v", g.director.intensity)
+	}
+
+	// slow, but nobody died
+	g.run(1)
+	g.run(20)
+	if g.clear(); g.director.intensity != 1 {
+		t.Errorf("after a slow clear the intensity is %v", g.director.intensity)
+	}
+	if g.director.Done() || g.director.Wave() != 5 {
+		t.Errorf("an endless plan is done %v at wave %d", g.director.Done(), g.director.Wave())
+	}
+}
+
+func TestReset(t *testing.T) {
+	g := newGame(t, twoWaves, 1)
+	g.run(3)
+	if len(g.spawned) == 0 {
+		t.Fatal("nothing spawned")
+	}
+	g.director.Reset()
+	for _, d := range g.spawned {
+		if !d.obj.Delete {
+			t.Error("a drone is still around after a reset")
+		}
+	}
+	if g.director.Wave() != 0 || g.director.Cleared() != 0 {
+		t.Errorf("after a reset it's wave %d with %d cleared", g.director.Wave(), g.director.Cleared())
+	}
+	start := g.elapsed
+	if at := g.run(2.25); !slices.Equal(at, []float64{start + 2.25}) {
+		t.Errorf("%v seconds after a reset the first wave spawned at %v", start, at)
+	}
+}
+
+// TestVanished checks an enemy that goes without being killed comes back, instead of counting towards clearing the wave
+func TestVanished(t *testing.T) {
+	g := newGame(t, twoWaves, 1)
+	g.run(5)
+	g.destroy(g.spawned[0])
+	g.spawned[1].obj.Delete = true // like a turret whose player left used to
+	g.destroy(g.spawned[2])
+	if at := g.run(0.25); len(at) != 1 || g.director.Cleared() != 0 {
+		t.Fatalf("after one went without being killed %v spawned, and %d are cleared", at, g.director.Cleared())
+	}
+	g.kill()
+	if g.run(0.25); g.director.Cleared() != 1 {
+		t.Errorf("once the one that came back was killed %d are cleared", g.director.Cleared())
+	}
+}
+
+// TestRetarget checks enemies are given someone else to go after when their player leaves
+func TestRetarget(t *testing.T) {
+	g := newGame(t, twoWaves, 2)
+	g.run(2.25)
+	if len(g.spawned) != 1 {
+		t.Fatalf("%d spawned", len(g.spawned))
+	}
+	retarget := g.spawned[0].retarget
+	if retarget == nil {
+		t.Fatal("a drone can't be retargeted")
+	}
+
+	g.players[0].Delete = true
+	for range 10 {
+		if got := retarget(); got != g.players[1] {
+			t.Fatalf("with the first player gone it got %p, want the second player %p", got, g.players[1])
+		}
+	}
+	g.players[1].Delete = true
+	if got := retarget(); got != nil {
+		t.Errorf("with everybody gone it got %p", got)
+	}
+}
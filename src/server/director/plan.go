@@ -0,0 +1,94 @@
+package director
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/server/entity"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	assets "Geomyidae"
+)
+
+// Plan is a wave file: the waves to play, and how the director adjusts them as it goes.
+// Enemies appear at the map's spawn points, see New.
+// See assets/waves/survival.json for an example.
+type Plan struct {
+	Waves []Wave `json:"waves"`
+	// Endless keeps replaying the last wave, a little harder every time, instead of ending after it
+	Endless bool `json:"endless"`
+
+	// TimeScale adds this fraction more enemies for every minute the round has gone on
+	TimeScale float64 `json:"time_scale"`
+	// If a wave is cleared in less than FastClear seconds without anyone dying, the next one is PerformanceStep harder.
+	// If everyone died during a wave, the next one is PerformanceStep easier.
+	FastClear       float64 `json:"fast_clear"`
+	PerformanceStep float64 `json:"performance_step"`
+	// MinIntensity and MaxIntensity bound how much easier or harder performance can make the waves
+	MinIntensity float64 `json:"min_intensity"`
+	MaxIntensity float64 `json:"max_intensity"`
+}
+
+// Wave is one wave of enemies
+type Wave struct {
+	Name string `json:"name"`
+	// Break is how many seconds to wait before this wave starts
+	Break   float64 `json:"break"`
+	Enemies []Group `json:"enemies"`
+}
+
+// Group is some enemies of one type in a wave
+type Group struct {
+	Type constants.UserDataCode `json:"type"` // anything registered with the entity package, usually turret or tracker
+	// Count is how many spawn with one player, and PerPlayer how many more for each extra player
+	Count     int     `json:"count"`
+	PerPlayer float64 `json:"per_player"`
+	// Interval is how many seconds apart they spawn, so a wave trickles in instead of all appearing at once
+	Interval float64 `json:"interval"`
+}
+
+// DefaultPlan is the wave file the server uses unless it is given another one
+const DefaultPlan = "assets/waves/survival.json"
+
+// LoadDefault loads DefaultPlan from the embedded assets
+func LoadDefault() (*Plan, error) {
+	data, err := assets.FS.ReadFile(DefaultPlan)
+	if err != nil {
+		return nil, err
+	}
+	return Load(data)
+}
+
+// Load reads a wave file and checks it makes sense
+func Load(data []byte) (*Plan, error) {
+	plan := &Plan{}
+	err := json.Unmarshal(data, plan)
+	if err != nil {
+		return nil, fmt.Errorf("wave file: %w", err)
+	}
+	if len(plan.Waves) == 0 {
+		return nil, errors.New("wave file: there are no waves")
+	}
+	for i, wave := range plan.Waves {
+		for _, group := range wave.Enemies {
+			if !entity.Registered(group.Type) {
+				return nil, fmt.Errorf("wave file: wave %d has enemies of type %q, only %v can be spawned", i+1, group.Type, entity.Types())
+			}
+			// the director spawns them going after a player, and nothing else
+			err = entity.Check(group.Type, entity.Params{Target: entity.StandIn()})
+			if err != nil {
+				return nil, fmt.Errorf("wave file: wave %d: %w", i+1, err)
+			}
+			if group.Count < 0 || group.PerPlayer < 0 || group.Interval < 0 {
+				return nil, fmt.Errorf("wave file: wave %d has a negative count, per_player or interval", i+1)
+			}
+		}
+	}
+	if plan.MinIntensity <= 0 {
+		plan.MinIntensity = 1
+	}
+	if plan.MaxIntensity < plan.MinIntensity {
+		plan.MaxIntensity = plan.MinIntensity
+	}
+	return plan, nil
+}
@@ -0,0 +1,11 @@
+package main
+
+// Everything that can be spawned by name registers itself with the entity package when it is imported.
+// Nothing else in the server has to know about these, so they are imported here. A new kind of entity goes in this list.
+import (
+	_ "Geomyidae/server/bomb"
+	_ "Geomyidae/server/bullet"
+	_ "Geomyidae/server/pickup"
+	_ "Geomyidae/server/tracker"
+	_ "Geomyidae/server/turret"
+)
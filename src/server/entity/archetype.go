@@ -0,0 +1,273 @@
+package entity
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+
+	assets "Geomyidae"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// What each kind of entity looks like and how it plays, from its sprite to how fast it shoots, is its archetype.
+// Archetypes are read from an entities file when the server starts, so balancing doesn't need a recompile.
+// See assets/entities/entities.json.
+
+// Archetype is one kind of entity. Which fields matter depends on the kind, the rest are left out of the file.
+type Archetype struct {
+	Sprite *Sprite `json:"sprite"`
+	Shape  *Shape  `json:"shape"`
+	// Mass and Moment are the body's, Moment is how hard it is to spin
+	Mass     float64  `json:"mass"`
+	Moment   float64  `json:"moment"`
+	Material Material `json:"material"`
+	// Speed is how fast a bullet flies in meters a second, or how hard a tracker thrusts
+	Speed float64 `json:"speed"`
+	// Cooldown is how many seconds apart shots are fired. Players also have cooldowns for dropping bombs and switching portals.
+	Cooldown       float64 `json:"cooldown"`
+	BombCooldown   float64 `json:"bomb_cooldown"`
+	PortalCooldown float64 `json:"portal_cooldown"`
+	// Lifetime is how many seconds a bullet flies, or how long a bomb's fuse is
+	Lifetime float64 `json:"lifetime"`
+	// Damage is how many hit points it takes from a player it hits
+	Damage int `json:"damage"`
+	// Bombs is how many bombs a player starts with, and Shrapnel how many pieces a bomb bursts into
+	Bombs    int `json:"bombs"`
+	Shrapnel int `json:"shrapnel"`
+	// Drops are spawned where it is destroyed
+	Drops []Drop `json:"drops"`
+}
+
+// Sprite is where an entity's picture is on a sprite sheet, in pixels
+type Sprite struct {
+	Sheet        string `json:"sheet"`
+	X            int    `json:"x"`
+	Y            int    `json:"y"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	FlipVertical bool   `json:"flip_vertical"`
+}
+
+// Shape is an entity's collision shape, in meters around its middle
+type Shape struct {
+	Kind   string  `json:"kind"` // box or circle
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	Radius float64 `json:"radius"`
+}
+
+// Material is what an entity's shape is like to bump into
+type Material struct {
+	Elasticity float64 `json:"elasticity"`
+	Density    float64 `json:"density"`
+	Friction   float64 `json:"friction"`
+}
+
+// Drop is something spawned by name, see New
+type Drop struct {
+	Type       constants.UserDataCode `json:"type"`
+	Properties map[string]string      `json:"properties"`
+}
+
+// DefaultArchetypes is the entities file the server uses unless it is given another one
+const DefaultArchetypes = "assets/entities/entities.json"
+
+// required are the archetypes something uses, and whether it needs a body out of its archetype
+var required = make(map[string]bool)
+
+// positive are the fields each archetype needs more than 0, by their names in the entities file
+var positive = make(map[string][]string)
+
+// archetypes are the ones in use, see UseArchetypes
+var archetypes map[string]Archetype
+
+// Requires says an archetype called name has to be in the entities file. withBody means it needs a sprite and a shape.
+// Everything registered with Register requires the archetype of the same name with a body.
+// Like Register, it is meant to be called from init.
+func Requires(name string, withBody bool) {
+	required[name] = required[name] || withBody
+}
+
+// RequiresPositive says fields of the archetype called name have to be more than 0, because whatever is built out of it
+// divides by them or doesn't work without them. Fields are named as in the entities file: speed, cooldown, lifetime or
+// shrapnel. Like Register, it is meant to be called from init, and panics on any other field.
+func RequiresPositive(name string, fields ...string) {
+	for _, field := range fields {
+		if _, ok := (Archetype{}).number(field); !ok {
+			panic(fmt.Sprintf("entity: %v can't require %q to be positive", name, field))
+		}
+	}
+	positive[name] = append(positive[name], fields...)
+}
+
+// LoadArchetypes reads an entities file and checks it makes sense
+func LoadArchetypes(data []byte) (map[string]Archetype, error) {
+	loaded := make(map[string]Archetype)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields() // so a typo doesn't quietly leave something at zero
+	err := decoder.Decode(&loaded)
+	if err != nil {
+		return nil, fmt.Errorf("entities file: %w", err)
+	}
+	for _, name := range slices.Sorted(maps.Keys(loaded)) {
+		if _, ok := required[name]; !ok {
+			return nil, fmt.Errorf("entities file: nothing is called %q, the archetypes are %v", name, slices.Sorted(maps.Keys(required)))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(required)) {
+		a, ok := loaded[name]
+		if !ok {
+			return nil, fmt.Errorf("entities file: %v is missing", name)
+		}
+		err = a.check(name)
+		if err != nil {
+			return nil, fmt.Errorf("entities file: %v: %w", name, err)
+		}
+	}
+
+	// drops are built once out of the new archetypes, the way they will be dropped
+	previous := archetypes
+	archetypes = loaded
+	defer func() { archetypes = previous }()
+	for _, name := range slices.Sorted(maps.Keys(loaded)) {
+		for _, drop := range loaded[name].Drops {
+			err = Check(drop.Type, Params{Properties: drop.Properties})
+			if err != nil {
+				return nil, fmt.Errorf("entities file: %v: can't drop %w", name, err)
+			}
+		}
+	}
+	return loaded, nil
+}
+
+// LoadDefaultArchetypes loads DefaultArchetypes from the embedded assets
+func LoadDefaultArchetypes() (map[string]Archetype, error) {
+	data, err := assets.FS.ReadFile(DefaultArchetypes)
+	if err != nil {
+		return nil, err
+	}
+	return LoadArchetypes(data)
+}
+
+// UseArchetypes makes loaded the archetypes everything is built from. It has to be called before anything is built.
+func UseArchetypes(loaded map[string]Archetype) {
+	archetypes = loaded
+}
+
+// ArchetypeOf is the archetype called name
+func ArchetypeOf(name string) Archetype {
+	a, ok := archetypes[name]
+	if !ok {
+		panic(fmt.Sprintf("entity: there is no %v archetype, call UseArchetypes first", name))
+	}
+	return a
+}
+
+func (a Archetype) check(name string) error {
+	if required[name] {
+		if a.Sprite == nil || a.Shape == nil {
+			return errors.New("it needs a sprite and a shape")
+		}
+		if a.Mass <= 0 || a.Moment <= 0 {
+			return errors.New("mass and moment have to be more than 0")
+		}
+	}
+	if a.Sprite != nil && (a.Sprite.Sheet == "" || a.Sprite.Width <= 0 || a.Sprite.Height <= 0) {
+		return errors.New("the sprite needs a sheet, a width and a height")
+	}
+	if a.Shape != nil {
+		switch a.Shape.Kind {
+		case "box":
+			if a.Shape.Width <= 0 || a.Shape.Height <= 0 {
+				return errors.New("a box shape needs a width and a height")
+			}
+		case "circle":
+			if a.Shape.Radius <= 0 {
+				return errors.New("a circle shape needs a radius")
+			}
+		default:
+			return fmt.Errorf("shapes can be a box or a circle, not %q", a.Shape.Kind)
+		}
+	}
+	if a.Material.Elasticity < 0 || a.Material.Density < 0 || a.Material.Friction < 0 {
+		return errors.New("elasticity, density and friction can't be negative")
+	}
+	if min(a.Speed, a.Cooldown, a.BombCooldown, a.PortalCooldown, a.Lifetime) < 0 || min(a.Damage, a.Bombs, a.Shrapnel) < 0 {
+		return errors.New("speed, cooldowns, lifetime, damage, bombs and shrapnel can't be negative")
+	}
+	for _, field := range positive[name] {
+		if value, _ := a.number(field); value <= 0 {
+			return fmt.Errorf("%v has to be more than 0", field)
+		}
+	}
+	for _, drop := range a.Drops {
+		if !Registered(drop.Type) {
+			return fmt.Errorf("it drops %q, only %v can be spawned", drop.Type, Types())
+		}
+	}
+	return nil
+}
+
+// number is the field called name in the entities file, for RequiresPositive
+func (a Archetype) number(name string) (float64, bool) {
+	switch name {
+	case "speed":
+		return a.Speed, true
+	case "cooldown":
+		return a.Cooldown, true
+	case "lifetime":
+		return a.Lifetime, true
+	case "shrapnel":
+		return float64(a.Shrapnel), true
+	}
+	return 0, false
+}
+
+// NewBody makes a body at x, y in meters with the archetype's shape on it
+func (a Archetype) NewBody(x, y float64) (*cp.Body, *cp.Shape) {
+	body := cp.NewBody(a.Mass, a.Moment)
+	var shape *cp.Shape
+	if a.Shape.Kind == "circle" {
+		shape = cp.NewCircle(body, a.Shape.Radius, cp.Vector{})
+	} else {
+		shape = cp.NewBox(body, a.Shape.Width, a.Shape.Height, 0)
+	}
+	shape.SetElasticity(a.Material.Elasticity)
+	shape.SetDensity(a.Material.Density)
+	shape.SetFriction(a.Material.Friction)
+	body.AddShape(shape)
+	body.SetPosition(cp.Vector{X: x, Y: y})
+	return body, shape
+}
+
+// Dress gives obj the archetype's sprite
+func (a Archetype) Dress(obj *shared_structs.GameObject) {
+	obj.Sprite = a.Sprite.Sheet
+	obj.SpriteOffsetX = a.Sprite.X
+	obj.SpriteOffsetY = a.Sprite.Y
+	obj.SpriteWidth = a.Sprite.Width
+	obj.SpriteHeight = a.Sprite.Height
+	obj.SpriteFlipVertical = a.Sprite.FlipVertical
+}
+
+// SpawnDrops spawns the archetype's drops at x, y
+func (a Archetype) SpawnDrops(x, y float64, spawnerPipeline chan shared_structs.HasBehavior) {
+	for _, drop := range a.Drops {
+		obj, err := New(drop.Type, Params{X: x, Y: y, Properties: drop.Properties})
+		if err != nil {
+			log.Printf("not dropping a %v: %v", drop.Type, err)
+			continue
+		}
+		select {
+		case spawnerPipeline <- obj:
+		default:
+		}
+	}
+}
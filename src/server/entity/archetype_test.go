@@ -0,0 +1,171 @@
+package entity_test
+
+import (
+	"Geomyidae/server/entity"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	assets "Geomyidae"
+
+	// everything that registers itself or requires an archetype, the way the server imports them
+	_ "Geomyidae/server/bomb"
+	_ "Geomyidae/server/bullet"
+	_ "Geomyidae/server/pickup"
+	_ "Geomyidae/server/player"
+	_ "Geomyidae/server/tracker"
+	_ "Geomyidae/server/turret"
+)
+
+func TestLoadDefaultArchetypes(t *testing.T) {
+	loaded, err := entity.LoadDefaultArchetypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded["bullet"].Damage <= 0 {
+		t.Errorf("bullets do %d damage", loaded["bullet"].Damage)
+	}
+}
+
+func TestLoadArchetypesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(file map[string]map[string]any)
+		contains string
+	}{
+		{
+			name:     "unknown field",
+			change:   func(file map[string]map[string]any) { file["turret"]["cooldwon"] = 5 },
+			contains: `unknown field "cooldwon"`,
+		},
+		{
+			name:     "unknown name",
+			change:   func(file map[string]map[string]any) { file["turet"] = file["turret"] },
+			contains: `nothing is called "turet"`,
+		},
+		{
+			name:     "missing",
+			change:   func(file map[string]map[string]any) { delete(file, "tracker") },
+			contains: "tracker is missing",
+		},
+		{
+			name:     "no sprite",
+			change:   func(file map[string]map[string]any) { delete(file["bomb"], "sprite") },
+			contains: "bomb: it needs a sprite and a shape",
+		},
+		{
+			name:     "no mass",
+			change:   func(file map[string]map[string]any) { file["pickup"]["mass"] = 0 },
+			contains: "pickup: mass and moment have to be more than 0",
+		},
+		{
+			name: "bad shape",
+			change: func(file map[string]map[string]any) {
+				file["bomb"]["shape"] = map[string]any{"kind": "triangle", "width": 1}
+			},
+			contains: `bomb: shapes can be a box or a circle, not "triangle"`,
+		},
+		{
+			name: "circle without a radius",
+			change: func(file map[string]map[string]any) {
+				file["bullet"]["shape"] = map[string]any{"kind": "circle", "width": 1, "height": 1}
+			},
+			contains: "bullet: a circle shape needs a radius",
+		},
+		{
+			name:     "negative",
+			change:   func(file map[string]map[string]any) { file["player"]["cooldown"] = -1 },
+			contains: "player: speed, cooldowns, lifetime, damage, bombs and shrapnel can't be negative",
+		},
+		{
+			name:     "bomb without shrapnel",
+			change:   func(file map[string]map[string]any) { file["bomb"]["shrapnel"] = 0 },
+			contains: "bomb: shrapnel has to be more than 0",
+		},
+		{
+			name:     "bomb without a fuse",
+			change:   func(file map[string]map[string]any) { delete(file["bomb"], "lifetime") },
+			contains: "bomb: lifetime has to be more than 0",
+		},
+		{
+			name:     "shrapnel that doesn't fly",
+			change:   func(file map[string]map[string]any) { file["shrapnel"]["speed"] = 0 },
+			contains: "shrapnel: speed has to be more than 0",
+		},
+		{
+			name:     "bullet that doesn't last",
+			change:   func(file map[string]map[string]any) { file["bullet"]["lifetime"] = 0 },
+			contains: "bullet: lifetime has to be more than 0",
+		},
+		{
+			name:     "tracker that doesn't move",
+			change:   func(file map[string]map[string]any) { delete(file["tracker"], "speed") },
+			contains: "tracker: speed has to be more than 0",
+		},
+		{
+			name: "unregistered drop",
+			change: func(file map[string]map[string]any) {
+				file["turret"]["drops"] = []any{map[string]any{"type": "coin"}}
+			},
+			contains: `turret: it drops "coin"`,
+		},
+		{
+			name: "drop that can't be built",
+			change: func(file map[string]map[string]any) {
+				file["turret"]["drops"] = []any{map[string]any{"type": "pickup"}}
+			},
+			contains: "turret: can't drop pickup: it needs a pickup property",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := entity.LoadArchetypes(changed(t, test.change))
+			if err == nil {
+				t.Fatal("it loaded")
+			}
+			if !strings.HasPrefix(err.Error(), "entities file: ") || !strings.Contains(err.Error(), test.contains) {
+				t.Errorf("got %q, want it to contain %q", err, test.contains)
+			}
+		})
+	}
+}
+
+// TestLoadArchetypesKeepsInUse checks that a file failing on its drops, which are built out of it, leaves the archetypes
+// in use alone
+func TestLoadArchetypesKeepsInUse(t *testing.T) {
+	loaded, err := entity.LoadDefaultArchetypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity.UseArchetypes(loaded)
+	_, err = entity.LoadArchetypes(changed(t, func(file map[string]map[string]any) {
+		file["turret"]["cooldown"] = 99
+		file["turret"]["drops"] = []any{map[string]any{"type": "pickup"}}
+	}))
+	if err == nil {
+		t.Fatal("it loaded")
+	}
+	if entity.ArchetypeOf("turret").Cooldown != loaded["turret"].Cooldown {
+		t.Error("the turret archetype in use changed")
+	}
+}
+
+// changed is the default entities file with change made to it
+func changed(t *testing.T, change func(file map[string]map[string]any)) []byte {
+	t.Helper()
+	data, err := assets.FS.ReadFile(entity.DefaultArchetypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file map[string]map[string]any
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	change(file)
+	data, err = json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,95 @@
+package entity
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// Every kind of thing that can be spawned by name registers a constructor here against its UserDataCode,
+// in its package's init. Action sequences, map object layers and the wave director spawn through New,
+// so a new enemy only has to register itself and be imported by the server's main package to be usable everywhere.
+
+// Params is everything a constructor might want. Each type uses what it needs and says so if something is missing.
+type Params struct {
+	// X and Y are where it appears, in meters
+	X float64
+	Y float64
+	// Target is who an enemy goes after
+	Target *shared_structs.GameObject
+	// Retarget picks someone else for an enemy to go after once Target is gone. Without it the enemy goes with its target.
+	// It returns nil while there is nobody around, and the enemy waits and asks again.
+	Retarget func() *shared_structs.GameObject
+	// Owner is who it belongs to, and who gets the credit for what it does
+	Owner *shared_structs.GameObject
+	// Properties are anything else, like the kind of a pickup. On the map they are the object's custom properties.
+	Properties map[string]string
+}
+
+// Constructor builds one entity out of params
+type Constructor func(params Params) (shared_structs.HasBehavior, error)
+
+var constructors = make(map[constants.UserDataCode]Constructor)
+
+// Register makes code spawnable by name. It is meant to be called from init, and panics if code is already registered.
+func Register(code constants.UserDataCode, constructor Constructor) {
+	if _, ok := constructors[code]; ok {
+		panic(fmt.Sprintf("entity: %q is registered twice", code))
+	}
+	constructors[code] = constructor
+	Requires(string(code), true)
+}
+
+// Registered reports whether code can be spawned
+func Registered(code constants.UserDataCode) bool {
+	_, ok := constructors[code]
+	return ok
+}
+
+// Types are every type that can be spawned, in order
+func Types() []constants.UserDataCode {
+	return slices.Sorted(maps.Keys(constructors))
+}
+
+// New builds an entity of type code
+func New(code constants.UserDataCode, params Params) (shared_structs.HasBehavior, error) {
+	constructor, ok := constructors[code]
+	if !ok {
+		return nil, fmt.Errorf("%q can't be spawned, only %v", code, Types())
+	}
+	obj, err := constructor(params)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", code, err)
+	}
+	return obj, nil
+}
+
+// Check builds an entity of type code out of params and throws it away. Files that say to spawn something later, like
+// wave files and maps, call it when they are loaded with the params that will be passed to New, so something that can't be
+// spawned like that, such as a bullet with no owner, is an error then instead of a log line in the middle of a game.
+// Where New will get a player as the target or owner, pass StandIn.
+func Check(code constants.UserDataCode, params Params) error {
+	_, err := New(code, params)
+	return err
+}
+
+// StandIn returns an object to pass to Check in place of a player, with a body somewhere on the map
+func StandIn() *shared_structs.GameObject {
+	body := cp.NewBody(1, 1)
+	return &shared_structs.GameObject{Body: body, Identity: constants.Player}
+}
+
+// Placement is an entity placed on the map, for a game mode to spawn
+type Placement struct {
+	Type constants.UserDataCode
+	Params
+}
+
+// Spawn builds the placed entity
+func (p Placement) Spawn() (shared_structs.HasBehavior, error) {
+	return New(p.Type, p.Params)
+}
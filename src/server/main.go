@@ -2,34 +2,64 @@ package main
 
 import (
 	"Geomyidae/internal/constants"
-	"Geomyidae/server/pickup"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/clock"
+	"Geomyidae/server/collision"
+	"Geomyidae/server/combat"
+	"Geomyidae/server/director"
+	"Geomyidae/server/entity"
+	"Geomyidae/server/message"
+	"Geomyidae/server/mode"
 	"Geomyidae/server/player"
+	"Geomyidae/server/snapshot"
 	"Geomyidae/server/sock_server"
 	"Geomyidae/server/tile"
-	"Geomyidae/server/turret"
-	"encoding/json"
+	"flag"
+	"log"
+	"os"
 	"sort"
-	"time"
 
-	"github.com/google/uuid"
 	"github.com/jakecoffman/cp/v2"
-
-	assets "Geomyidae"
-	"Geomyidae/internal/shared_structs"
-	"Geomyidae/internal/tiled"
-	"log"
 )
 
 var physics *cp.Space
 
-const metersToPixels = 64
+const metersToPixels = constants.MetersToPixels
+
+// scoreboardInterval is how often, in seconds, the scoreboard is pushed to every client
+const scoreboardInterval = 1.0
+
+var tickRate = flag.Int("tickrate", 50, "simulation ticks per second")
+var maxCatchUp = flag.Int("catchup", 5, "most ticks to simulate back to back when the server falls behind")
+var gracePeriod = flag.Float64("grace", player.DefaultGracePeriod, "seconds a disconnected player's ship waits for them to reconnect")
+var respawnTime = flag.Float64("respawn", player.DefaultRespawnTime, "seconds a dead player waits to respawn")
+var spawnPoints spawnList
+var gameMode = flag.String("mode", "", "the game mode: sandbox, ffa, tdm or coop. If it isn't set, the map's mode property is used")
+var timeLimit = flag.Float64("timelimit", 600, "seconds a round lasts in modes with a time limit, 0 for no limit")
+var scoreLimit = flag.Int("scorelimit", 0, "the score that wins a round, 0 for the mode's default")
+var waveFile = flag.String("waves", "", "a wave file for co-op, see "+director.DefaultPlan+" for an example. The default waves are used if it isn't set")
+var entityFile = flag.String("entities", "", "an entities file with what everything looks like and how it plays, see "+entity.DefaultArchetypes+". The default one is used if it isn't set")
+var friendlyFire = flag.Bool("friendlyfire", false, "let players hurt each other, and enemies hurt each other")
+var selfDamage = flag.Bool("selfdamage", false, "let players hurt themselves with their own bullets and bombs")
+
+// round runs the game mode
+var round *mode.Round
+
+func init() {
+	flag.Var(&spawnPoints, "spawn", "a spawn point for players as x,y in meters, can be given more than once")
+}
 
-var prevTime time.Time
+// world is the most recent snapshot of everything.
+// Each client gets the part of it they can see, as the difference from the last snapshot that client acknowledged.
+var world snapshot.Snapshot
 
 // players is a subset of simulationObjects. Every value it contains is a duplicate
 var players *player.List
 var simulationObjects []shared_structs.HasBehavior
 
+// scenery is the current map's tiles, for snapshot.Visible. They aren't in the physics space, walls are instead.
+var scenery *snapshot.Scenery
+
 // simulationObjects may not look like a slice of pointers, but it is
 // because HasBehavior is implemented with pointer receiver methods
 
@@ -37,31 +67,46 @@ var simulationObjects []shared_structs.HasBehavior
 // players is however, because it is updated remotely by the socket server
 // apOb allows players to add new NetworkPlayers to simulationObjects remotely
 func apOb(networkPlayer *player.NetworkPlayer) {
+	collision.Attach(networkPlayer)
 	simulationObjects = append(simulationObjects, networkPlayer)
 }
 
 func main() {
-	// Import tile data
-	tileByteInput, err := assets.FS.ReadFile("assets/tiled/test-one.tmx")
+	flag.Parse()
+
+	// what everything is made of, before anything is made
+	archetypes, err := loadArchetypes(*entityFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	entity.UseArchetypes(archetypes)
+
+	// the maps to play, the first one is loaded straight away
+	watchFromDisk()
+	rotation, err = loadRotation(*rotationFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	first, err := loadLevel(rotation[0].Map)
 	if err != nil {
 		log.Fatal(err)
 	}
-	tileData := tiled.GetTileData(tileByteInput)
+	next = 1 % len(rotation)
 
 	// instantiate chipmunk
 	physics = cp.NewSpace()
 	/*
-	TL;DR: Without calling UseSpatialHash before setting SleepTimeThreshold,
-	the physics engine panics when trying to put sleeping bodies to sleep.
+		TL;DR: Without calling UseSpatialHash before setting SleepTimeThreshold,
+		the physics engine panics when trying to put sleeping bodies to sleep.
 
-	Detailed
-	Summary: The crash was caused by the chipmunk physics library's sleeping/deactivation system attempting to use spatial hashing that wasn't initialized. By calling UseSpatialHash(1, 50) before setting SleepTimeThreshold, the broad-phase collision detection is properly set up to handle sleeping bodies, and the panic is resolved.
+		Detailed
+		Summary: The crash was caused by the chipmunk physics library's sleeping/deactivation system attempting to use spatial hashing that wasn't initialized. By calling UseSpatialHash(1, 50) before setting SleepTimeThreshold, the broad-phase collision detection is properly set up to handle sleeping bodies, and the panic is resolved.
 
-	The parameters (1, 50) mean:
+		The parameters (1, 50) mean:
 
-	1 = grid size in meters (adjust based on your typical object sizes)
-	50 = number of buckets for the hash table
-	You can tune these values based on your game's performance needs.
+		1 = grid size in meters (adjust based on your typical object sizes)
+		50 = number of buckets for the hash table
+		You can tune these values based on your game's performance needs.
 	*/
 	physics.UseSpatialHash(1, 50)
 	// Set IdleSpeedThreshold to a reasonable value. This specifies the maximum velocity (in units/second) below which a body is considered "idle" and can potentially sleep. I set it to 1.0 - bodies moving slower than 1 unit/second will become eligible for sleeping after remaining idle for SleepTimeThreshold (0.5 seconds).
@@ -69,169 +114,198 @@ func main() {
 	// Set SleepTimeThreshold to 0.5 seconds. This means that if a body remains idle (below the IdleSpeedThreshold) for 0.5 seconds, it will be put to sleep.
 	// Without this, non-static bodies never go to sleep
 	physics.SleepTimeThreshold = 0.5
+	tile.HandleOneWay(physics)
+	collision.Install(physics, collisions, combat.Rules{FriendlyFire: *friendlyFire, SelfDamage: *selfDamage})
 	players = player.NewList(physics, apOb)
+	players.GracePeriod = *gracePeriod
+	players.RespawnTime = *respawnTime
 
 	spawnerPipeline := make(chan shared_structs.HasBehavior, 10)
 
-	for _, td := range tileData {
-		if td.ID == 0 {
-			continue // empty tile
-		}
-		body := cp.NewStaticBody()
-		shape := cp.NewBox(body, 1, 1, 0)
-		shape.SetElasticity(0.25)
-		shape.SetDensity(0.5)
-		shape.SetFriction(1.0)
-		body.AddShape(shape)
-		body.SetPosition(cp.Vector{X: float64(td.Col) + 0.5, Y: float64(td.Row) + 0.5})
-
-		obj := tile.NewTile(
-			&shared_structs.GameObject{
-				Sprite:               td.Sprite,
-				SpriteOffsetX:        td.SpriteOffsetX,
-				SpriteOffsetY:        td.SpriteOffsetY,
-				SpriteWidth:          td.SpriteWidth,
-				SpriteHeight:         td.SpriteHeight,
-				SpriteFlipHorizontal: td.SpriteFlipHorizontal,
-				SpriteFlipVertical:   td.SpriteFlipVertical,
-				SpriteFlipDiagonal:   td.SpriteFlipDiagonal,
-				Angle:                shared_structs.RoundedFloat2(body.Angle()),
-				UUID:                 uuid.New().String(),
-				Body:                 body,
-				Shape:                shape,
-				IsStatic:             true,
-				Identity:             constants.Tile,
-			}, nil)
-		body.UserData = obj.GameObject
-
-		physics.AddShape(shape)
-		simulationObjects = append(simulationObjects, obj)
-		physics.AddBody(body)
-	}
-
-	// make an action block to trigger a spawn sequence
-	col, row := 7, 7
-	body := cp.NewStaticBody()
-	shape := cp.NewBox(body, 1, 1, 0)
-	shape.SetElasticity(0.25)
-	shape.SetDensity(0.5)
-	shape.SetFriction(1.0)
-	body.AddShape(shape)
-	body.SetPosition(cp.Vector{X: float64(col) + 0.5, Y: float64(row) + 0.5})
-
-	seq := make([]tile.Action, 6)
-	seq[0] = tile.Action{
-		Seconds: 1,
-		Type:    constants.Turret,
-		X:       8,
-		Y:       8,
-	}
-	seq[1] = tile.Action{
-		Seconds: 2,
-		Type:    constants.Turret,
-		X:       3,
-		Y:       3,
-	}
-	seq[2] = tile.Action{
-		Seconds: 2,
-		Type:    constants.Turret,
-		X:       2,
-		Y:       8,
-	}
-	seq[3] = tile.Action{
-		Seconds: 2,
-		Type:    constants.Turret,
-		X:       1,
-		Y:       3,
+	players.WriteAccess.Lock()
+	err = enterLevel(first, rotation[0])
+	players.WriteAccess.Unlock()
+	if err != nil {
+		log.Fatal(err)
 	}
-	seq[4] = tile.Action{Seconds: 2, Type: constants.Turret, X: 8, Y: 3}
-	seq[5] = tile.Action{Seconds: 2, Type: constants.Tracker, X: 9, Y: 3}
-
-	obj := tile.NewTile(
-		&shared_structs.GameObject{
-			Sprite:               "platformerPack_industrial_tilesheet_64x64",
-			SpriteOffsetX:        300,
-			SpriteOffsetY:        100,
-			SpriteWidth:          64,
-			SpriteHeight:         64,
-			SpriteFlipHorizontal: false,
-			SpriteFlipVertical:   false,
-			SpriteFlipDiagonal:   false,
-			Angle:                shared_structs.RoundedFloat2(body.Angle()),
-			UUID:                 uuid.New().String(),
-			Body:                 body,
-			Shape:                shape,
-			IsStatic:             true,
-			Identity:             constants.Tile,
-		}, seq)
-	body.UserData = obj.GameObject
-
-	physics.AddShape(shape)
-	simulationObjects = append(simulationObjects, obj)
-	physics.AddBody(body)
-
-	newPickup := pickup.NewPickup(7, 7, "bombplus")
-	physics.AddShape(newPickup.Shape)
-	physics.AddBody(newPickup.Body)
-	simulationObjects = append(simulationObjects, newPickup)
+	startWatching()
 
 	// kick off socket server
 	hub := sock_server.Api(players)
 
-	prevTime = time.Now()
+	simClock := clock.New(*tickRate, *maxCatchUp)
+	var scoreboard []shared_structs.PlayerScore
+	var match *shared_structs.MatchState
+	var lastScoreboard uint64
 	for {
-		includeStaticAndAsleep := false
-		players.WriteAccess.Lock()
-		deltaTime := time.Now().Sub(prevTime).Seconds()
-		countTurrets := 0
+		steps := simClock.Steps()
+		if steps == 0 {
+			simClock.Wait()
+			continue
+		}
+
+		for i := 0; i < steps; i++ {
+			step(simClock.Next(), spawnerPipeline)
+		}
+
+		pushScoreboard := simClock.Current() >= lastScoreboard+uint64(scoreboardInterval*float64(*tickRate)) || mapChanged
+		if pushScoreboard {
+			scoreboard = players.Scoreboard()
+		}
 
+		// the socket goroutines add ships to the space and steer them with WriteAccess held, so every snapshot is put
+		// together with it held too. Only the encoding is left until after.
+		views := make(map[*sock_server.Client]*shared_structs.WorldData)
+		players.WriteAccess.Lock()
 		for _, obj := range simulationObjects {
-			obj.ApplyBehavior(deltaTime, spawnerPipeline)
 			gameObj := obj.GetObject()
-			if gameObj.NeedsStatics {
-				log.Println("full packet")
-				includeStaticAndAsleep = true
-				gameObj.NeedsStatics = false
-			}
-			_, ok := obj.(*turret.Turret)
-			if ok {
-				countTurrets++
+			if gameObj.Body == nil {
+				continue
 			}
+			pos := gameObj.Body.Position()
+			gameObj.X = int(pos.X * metersToPixels)
+			gameObj.Y = int(pos.Y * metersToPixels)
+			gameObj.Angle = shared_structs.RoundedFloat2(gameObj.Body.Angle())
+		}
+
+		world = snapshot.Capture(world, simulationObjects)
+		if pushScoreboard {
+			match = round.State()
+			lastScoreboard = simClock.Current()
 		}
+		hub.EachClient(func(sock *sock_server.Client) {
+			if mapChanged {
+				// nothing from the old map is worth a delta
+				sock.History.Reset()
+			}
+			data := shared_structs.WorldData{Tick: simClock.Current()}
+			view := snapshot.Visible(physics, scenery, sock.Player.Body.Position(), world)
+			data.Baseline, data.Objects = sock.History.Delta(data.Tick, view)
+			data.GameData.PlayerUUID = sock.Player.UUID
+			data.GameData.Portal = sock.Player.Portal
+			data.GameData.TickRate = *tickRate
+			data.GameData.Ship = sock.Player.ShipState()
+			data.GameData.Health, data.GameData.RespawnIn = sock.Player.Health()
+			data.GameData.MaxHealth = player.MaxHealth
+			if data.Baseline == 0 {
+				// the client is starting from scratch, maybe after a reconnect, so remind it how to reconnect
+				data.GameData.Session = sock.Player.Session
+			}
+			if pushScoreboard || data.Baseline == 0 {
+				data.Scoreboard = scoreboard
+				data.Match = match
+			}
+			views[sock] = &data
+		})
+		mapChanged = false
+		players.WriteAccess.Unlock()
 
+		// clients that joined since don't have a snapshot yet, and the ones that left aren't called
+		hub.EachClient(func(sock *sock_server.Client) {
+			data, ok := views[sock]
+			if !ok {
+				return
+			}
+			if !sock.Queue(sock.Encode(data)) {
+				log.Println("client is not keeping up, dropped a snapshot")
+			}
+		})
+	}
+}
+
+// loadWaves loads the wave file at path, or the default one if path is empty
+func loadWaves(path string) (*director.Plan, error) {
+	if path == "" {
+		return director.LoadDefault()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return director.Load(data)
+}
+
+// loadArchetypes loads the entities file at path, or the default one if path is empty
+func loadArchetypes(path string) (map[string]entity.Archetype, error) {
+	if path == "" {
+		return entity.LoadDefaultArchetypes()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return entity.LoadArchetypes(data)
+}
+
+// spawn adds obj to the world
+func spawn(obj shared_structs.HasBehavior) {
+	gameObj := obj.GetObject()
+	collision.Attach(obj)
+	if gameObj.Body != nil {
+		physics.AddBody(gameObj.Body)
+		physics.AddShape(gameObj.Shape)
+	}
+	simulationObjects = append(simulationObjects, obj)
+}
+
+// step advances the simulation by exactly one tick
+func step(tick shared_structs.Tick, spawnerPipeline chan shared_structs.HasBehavior) {
+	players.WriteAccess.Lock()
+	for _, obj := range simulationObjects {
+		obj.ApplyBehavior(tick, spawnerPipeline)
+	}
+
+	round.Update(tick)
+
+	// spawn everything that was created this tick
+	for spawning := true; spawning; {
 		select {
 		case msg, ok := <-spawnerPipeline:
 			if ok {
-				obj := msg.GetObject()
-				physics.AddBody(obj.Body)
-				physics.AddShape(obj.Shape)
-				simulationObjects = append(simulationObjects, msg)
+				spawn(msg)
 			}
 		default:
+			spawning = false
 		}
+	}
 
-		prevTime = time.Now()
-		physics.Step(deltaTime)
-		players.WriteAccess.Unlock()
+	applyReload()
+	if round.Finished() {
+		nextMap()
+	}
 
-		for _, obj := range simulationObjects {
-			gameObj := obj.GetObject()
-			pos := gameObj.Body.Position()
-			gameObj.X = int(pos.X * metersToPixels)
-			gameObj.Y = int(pos.Y * metersToPixels)
-			gameObj.Angle = shared_structs.RoundedFloat2(gameObj.Body.Angle())
-		}
+	collision.Step(physics, tick, spawnerPipeline)
+	// messages sent this tick are read next tick
+	message.Deliver()
+	readMessages()
+	players.WriteAccess.Unlock()
 
-		data := collectWorldState(includeStaticAndAsleep)
-		pruneWorldState()
+	pruneWorldState()
+	readCombat()
+}
 
-		for sock := range hub.Clients {
-			data.GameData.PlayerUUID = sock.Player.UUID
-			data.GameData.Portal = sock.Player.Portal
-			msg, _ := json.Marshal(data)
-			sock.Send <- msg
+// readMessages logs the messages for everyone that the server itself cares about. The caller must hold WriteAccess.
+func readMessages() {
+	for _, envelope := range message.Broadcasts() {
+		switch msg := envelope.Message.(type) {
+		case message.TriggerFired:
+			log.Printf("%v set off trigger %v", msg.Player, envelope.From)
 		}
-		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// readCombat credits everything that was hit or killed this tick
+func readCombat() {
+	players.WriteAccess.Lock()
+	defer players.WriteAccess.Unlock()
+	kills, hits := combat.Drain()
+	for _, kill := range kills {
+		log.Printf("%v %v killed by %v, assists %v", kill.VictimType, kill.Victim, kill.Killer, kill.Assists)
+		players.Credit(kill)
+		round.Killed(kill)
+	}
+	for _, hit := range hits {
+		players.CreditHit(hit)
 	}
 }
 
@@ -242,8 +316,10 @@ func pruneWorldState() {
 	for index, obj := range simulationObjects {
 		gameObj := obj.GetObject()
 		if gameObj.Delete {
-			physics.RemoveBody(gameObj.Body)
-			physics.RemoveShape(gameObj.Shape)
+			if gameObj.Body != nil {
+				physics.RemoveBody(gameObj.Body)
+				physics.RemoveShape(gameObj.Shape)
+			}
 			removed = append(removed, gameObj)
 			removedIndexes = append(removedIndexes, index)
 		}
@@ -252,27 +328,12 @@ func pruneWorldState() {
 	players.WriteAccess.Lock()
 	defer players.WriteAccess.Unlock()
 	for _, gameObj := range removed {
-		delete(players.Players, gameObj.UUID)
+		players.Remove(gameObj.UUID)
 	}
 
 	simulationObjects = removeIndexes(simulationObjects, removedIndexes)
 }
 
-func collectWorldState(includeStaticAndAsleep bool) *shared_structs.WorldData {
-	data := shared_structs.WorldData{}
-	for _, obj := range simulationObjects {
-		gameObj := obj.GetObject()
-		if !includeStaticAndAsleep && gameObj.IsStatic && !gameObj.Delete {
-			continue
-		}
-		if !includeStaticAndAsleep && gameObj.Body.IsSleeping() {
-			continue
-		}
-		data.Objects = append(data.Objects, *obj.GetObject())
-	}
-	return &data
-}
-
 // removeIndexes removes elements from a slice at the given indices.
 // The indices should be sorted in descending order to avoid issues with shifting elements.
 func removeIndexes[T any](s []T, indexes []int) []T {
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/tiled"
+	"Geomyidae/server/entity"
+	"Geomyidae/server/tile"
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// What the map's object layers can hold, by class:
+//   - spawn: a spawn point for players, or for co-op enemies if its enemies property is true.
+//     Enemies appear at the players' spawn points on maps without any of their own.
+//   - trigger: a trigger tile, best placed as a tile object so it gets that tile's sprite.
+//     It sets off the sequence named by its sequence property, or by its name if that isn't set.
+//   - action: a step of a sequence, spawned where the action is placed. Its properties are sequence, the sequence it is
+//     part of, order, where in the sequence it goes, spawn, the type it spawns, delay, seconds to wait before it, and count.
+//     The rest of its properties are passed on to what it spawns.
+//   - anything registered with the entity package, like pickup: placed on the map, with the object's properties.
+//     A pickup needs the kind of pickup in its pickup property. Enemies need someone to go after, so they are spawned
+//     by actions instead.
+// Anything else is left alone, so designers can keep notes on the map.
+
+// mapObjects is what was placed on the map's object layers
+type mapObjects struct {
+	spawns      []cp.Vector
+	enemySpawns []cp.Vector
+	triggers    []tile.Trigger
+	placed      []entity.Placement
+}
+
+// triggerSprite is used for triggers that aren't tile objects
+var triggerSprite = tile.Trigger{
+	Sprite:        "platformerPack_industrial_tilesheet_64x64",
+	SpriteOffsetX: 300,
+	SpriteOffsetY: 100,
+	SpriteWidth:   64,
+	SpriteHeight:  64,
+}
+
+func loadMapObjects(objects []tiled.Object) (mapObjects, error) {
+	var loaded mapObjects
+	type step struct {
+		order  int
+		action tile.Action
+	}
+	sequences := make(map[string][]step)
+	var triggers []tiled.Object
+
+	for _, object := range objects {
+		switch object.Class {
+		case "spawn":
+			point := cp.Vector{X: object.X, Y: object.Y}
+			var enemies bool
+			if value, ok := object.Properties["enemies"]; ok {
+				var err error
+				enemies, err = strconv.ParseBool(value)
+				if err != nil {
+					return loaded, fmt.Errorf("map object %d: enemies: %w", object.ID, err)
+				}
+			}
+			if enemies {
+				loaded.enemySpawns = append(loaded.enemySpawns, point)
+			} else {
+				loaded.spawns = append(loaded.spawns, point)
+			}
+		case "trigger":
+			triggers = append(triggers, object)
+		case "action":
+			action, order, err := parseAction(object)
+			if err != nil {
+				return loaded, err
+			}
+			name := object.Properties["sequence"]
+			sequences[name] = append(sequences[name], step{order, action})
+		default:
+			code := constants.UserDataCode(object.Class)
+			if !entity.Registered(code) {
+				continue
+			}
+			placed := entity.Placement{
+				Type:   code,
+				Params: entity.Params{X: object.X, Y: object.Y, Properties: object.Properties},
+			}
+			// built once now, so anything wrong with it is found when the map loads
+			_, err := placed.Spawn()
+			if err != nil {
+				return loaded, fmt.Errorf("map object %d: %w", object.ID, err)
+			}
+			loaded.placed = append(loaded.placed, placed)
+		}
+	}
+
+	for _, object := range triggers {
+		name := cmp.Or(object.Properties["sequence"], object.Name)
+		steps, ok := sequences[name]
+		if !ok {
+			return loaded, fmt.Errorf("map object %d: trigger sets off sequence %q, which has no actions", object.ID, name)
+		}
+		slices.SortStableFunc(steps, func(a, b step) int {
+			return cmp.Compare(a.order, b.order)
+		})
+		trigger := triggerSprite
+		if object.Sprite != "" {
+			trigger = tile.Trigger{
+				Sprite:               object.Sprite,
+				SpriteOffsetX:        object.SpriteOffsetX,
+				SpriteOffsetY:        object.SpriteOffsetY,
+				SpriteWidth:          object.SpriteWidth,
+				SpriteHeight:         object.SpriteHeight,
+				SpriteFlipHorizontal: object.SpriteFlipHorizontal,
+				SpriteFlipVertical:   object.SpriteFlipVertical,
+				SpriteFlipDiagonal:   object.SpriteFlipDiagonal,
+			}
+		}
+		trigger.X, trigger.Y = object.X, object.Y
+		for _, s := range steps {
+			trigger.Actions = append(trigger.Actions, s.action)
+		}
+		loaded.triggers = append(loaded.triggers, trigger)
+	}
+	return loaded, nil
+}
+
+// parseAction reads an action object's properties
+func parseAction(object tiled.Object) (tile.Action, int, error) {
+	action := tile.Action{
+		Type:       constants.UserDataCode(object.Properties["spawn"]),
+		X:          object.X,
+		Y:          object.Y,
+		Properties: object.Properties,
+	}
+	if !entity.Registered(action.Type) {
+		return action, 0, fmt.Errorf("map object %d: actions can spawn %v, not %q", object.ID, entity.Types(), action.Type)
+	}
+	// a sequence spawns it with the player that set it off as the target
+	err := entity.Check(action.Type, entity.Params{X: action.X, Y: action.Y, Target: entity.StandIn(), Properties: action.Properties})
+	if err != nil {
+		return action, 0, fmt.Errorf("map object %d: %w", object.ID, err)
+	}
+	var order int
+	if value, ok := object.Properties["order"]; ok {
+		order, err = strconv.Atoi(value)
+		if err != nil {
+			return action, 0, fmt.Errorf("map object %d: order: %w", object.ID, err)
+		}
+	}
+	if value, ok := object.Properties["delay"]; ok {
+		action.Seconds, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return action, 0, fmt.Errorf("map object %d: delay: %w", object.ID, err)
+		}
+	}
+	if value, ok := object.Properties["count"]; ok {
+		action.Count, err = strconv.Atoi(value)
+		if err != nil {
+			return action, 0, fmt.Errorf("map object %d: count: %w", object.ID, err)
+		}
+	}
+	return action, order, nil
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/internal/tiled"
+	"Geomyidae/server/director"
+	"Geomyidae/server/mode"
+	"Geomyidae/server/player"
+	"Geomyidae/server/snapshot"
+	"Geomyidae/server/tile"
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+
+	assets "Geomyidae"
+
+	"github.com/google/uuid"
+	"github.com/jakecoffman/cp/v2"
+)
+
+// Maps are .tmx files, loaded by name from the -maps directory or the embedded assets.
+// The server plays them in the order of the rotation, changing map once the last round on one is over,
+// without dropping anyone's connection.
+
+// embeddedMaps is where maps are in the embedded assets
+const embeddedMaps = "assets/tiled"
+
+var mapDir = flag.String("maps", "", "a directory to load maps from, instead of the embedded "+embeddedMaps)
+var mapName = flag.String("map", "test-one", "the map to play, by file name without .tmx. -rotation replaces it")
+var rotationFile = flag.String("rotation", "", "a map rotation file, see "+embeddedMaps+"/rotation.json for an example")
+
+// rotationEntry is one map in the rotation and how it is played. Anything it doesn't set comes from the command line.
+type rotationEntry struct {
+	Map        string   `json:"map"`
+	Mode       string   `json:"mode"`
+	Rounds     int      `json:"rounds"` // how many rounds before the next map, 1 if it isn't set
+	TimeLimit  *float64 `json:"timelimit"`
+	ScoreLimit *int     `json:"scorelimit"`
+	Waves      string   `json:"waves"`
+}
+
+// rotation is every map the server plays, and next the index of the one after the current one
+var rotation []rotationEntry
+var next int
+
+// level is the world built from one map
+type level struct {
+	name       string
+	properties map[string]string
+	// tiles are drawn, and keys say where each one is. walls are the shapes things bump into, see wallKey.
+	tiles   []shared_structs.HasBehavior
+	keys    []tileKey
+	walls   map[string][]*cp.Shape
+	scenery *snapshot.Scenery
+	objects mapObjects
+}
+
+// tileKey is where a tile is on the map
+type tileKey struct {
+	layer string
+	col   int
+	row   int
+}
+
+// current is the level being played, and currentEntry how
+var current *level
+var currentEntry rotationEntry
+
+// mapChanged is set when the map changes, so every client is sent the new one from scratch
+var mapChanged bool
+
+// loadRotation reads the rotation file at path, or makes a rotation of just -map if path is empty.
+// Every map in it is loaded once to check that it works.
+func loadRotation(path string) ([]rotationEntry, error) {
+	if path == "" {
+		// one map, played forever
+		return []rotationEntry{{Map: *mapName}}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []rotationEntry
+	err = json.Unmarshal(data, &entries)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%v: there are no maps in the rotation", path)
+	}
+	for i, entry := range entries {
+		if entry.Rounds < 0 {
+			return nil, fmt.Errorf("%v: %v can't be played %d rounds", path, entry.Map, entry.Rounds)
+		}
+		if entry.Rounds == 0 {
+			entries[i].Rounds = 1
+		}
+		l, err := loadLevel(entry.Map)
+		if err != nil {
+			return nil, err
+		}
+		_, _, err = entry.settings(l)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v: %w", path, entry.Map, err)
+		}
+	}
+	return entries, nil
+}
+
+// settings picks the game mode and waves for entry on l. The rotation wins over the command line, which wins over the map.
+func (entry rotationEntry) settings(l *level) (mode.GameMode, *director.Plan, error) {
+	modeName := cmp.Or(entry.Mode, *gameMode, l.properties["mode"], mode.DefaultMode)
+	gm, err := mode.New(modeName)
+	if err != nil {
+		return nil, nil, err
+	}
+	waves, err := loadWaves(cmp.Or(entry.Waves, *waveFile))
+	if err != nil {
+		return nil, nil, err
+	}
+	return gm, waves, nil
+}
+
+// loadLevel loads the map called name and builds its tiles and walls, but doesn't put anything in the world
+func loadLevel(name string) (*level, error) {
+	var fsys fs.FS = assets.FS
+	file := path.Join(embeddedMaps, name+".tmx")
+	if *mapDir != "" {
+		fsys = os.DirFS(*mapDir)
+		file = name + ".tmx"
+	}
+	tileMap, err := tiled.Load(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+	tileData, err := tileMap.TileData()
+	if err != nil {
+		return nil, err
+	}
+	placed, err := tileMap.Objects()
+	if err != nil {
+		return nil, err
+	}
+	objects, err := loadMapObjects(placed)
+	if err != nil {
+		return nil, &tiled.Error{File: file, Err: err}
+	}
+	l := &level{name: name, properties: tileMap.MapProperties(), walls: make(map[string][]*cp.Shape), objects: objects}
+
+	// solid tiles are merged into bigger shapes before they go in the physics space, see tile.Merge.
+	// Tiles on layers with different offsets are on different grids, and tiles of different materials feel different,
+	// so they are merged separately. Tiles with collision shapes drawn in Tiled aren't merged at all.
+	type wallKind struct {
+		offset   cp.Vector
+		material tile.Material
+	}
+	solid := make(map[wallKind][]tile.Cell)
+	for _, td := range tileData {
+		if td.ID == 0 {
+			continue // empty tile
+		}
+		body := cp.NewStaticBody()
+		body.SetPosition(cp.Vector{X: float64(td.Col) + 0.5 + td.OffsetX, Y: float64(td.Row) + 0.5 + td.OffsetY})
+
+		obj := tile.NewTile(
+			&shared_structs.GameObject{
+				Sprite:               td.Sprite,
+				SpriteOffsetX:        td.SpriteOffsetX,
+				SpriteOffsetY:        td.SpriteOffsetY,
+				SpriteWidth:          td.SpriteWidth,
+				SpriteHeight:         td.SpriteHeight,
+				SpriteFlipHorizontal: td.SpriteFlipHorizontal,
+				SpriteFlipVertical:   td.SpriteFlipVertical,
+				SpriteFlipDiagonal:   td.SpriteFlipDiagonal,
+				Depth:                td.Depth,
+				Angle:                shared_structs.RoundedFloat2(body.Angle()),
+				UUID:                 uuid.New().String(),
+				Body:                 body,
+				IsStatic:             true,
+				Identity:             constants.Tile,
+			}, nil)
+		body.UserData = obj.GameObject
+		l.tiles = append(l.tiles, obj)
+		l.keys = append(l.keys, tileKey{layer: td.Layer, col: td.Col, row: td.Row})
+
+		if td.Role == tiled.RoleCollision {
+			material, isSolid, err := tile.NewMaterial(td.Properties)
+			if err != nil {
+				return nil, &tiled.Error{File: file, Layer: td.Layer, Err: fmt.Errorf("row %d, column %d: %w", td.Row, td.Col, err)}
+			}
+			offset := cp.Vector{X: td.OffsetX, Y: td.OffsetY}
+			cell := tile.Cell{Col: td.Col, Row: td.Row}
+			switch {
+			case !isSolid:
+			case len(td.Collision) > 0:
+				l.addWall(fmt.Sprint("shaped", cell, offset, td.Collision, material), tile.NewShaped(cell, offset, td.Collision, material))
+			default:
+				kind := wallKind{offset: offset, material: material}
+				solid[kind] = append(solid[kind], cell)
+			}
+		}
+		if td.Role == tiled.RoleParallax && (td.ParallaxX != 1 || td.ParallaxY != 1) {
+			obj.ParallaxX = shared_structs.RoundedFloat2(1 - td.ParallaxX)
+			obj.ParallaxY = shared_structs.RoundedFloat2(1 - td.ParallaxY)
+		}
+	}
+	for kind, cells := range solid {
+		for _, rect := range tile.Merge(cells) {
+			l.addWall(fmt.Sprint("box", rect, kind.offset, kind.material), tile.NewWall(rect, kind.offset, kind.material))
+		}
+	}
+	l.scenery = newScenery(l.tiles)
+	log.Printf("%v: %d tiles, %d walls", name, len(tileData), len(l.walls))
+	return l, nil
+}
+
+// addWall adds the shapes of wall. key is what the wall is made of and where,
+// walls with the same key are the same, so a reload can keep them.
+func (l *level) addWall(key string, wall *shared_structs.GameObject) {
+	wall.Body.EachShape(func(shape *cp.Shape) {
+		l.walls[key] = append(l.walls[key], shape)
+	})
+}
+
+// newScenery indexes tiles for snapshot.Visible
+func newScenery(tiles []shared_structs.HasBehavior) *snapshot.Scenery {
+	scenery := snapshot.NewScenery()
+	for _, obj := range tiles {
+		gameObj := obj.GetObject()
+		if gameObj.ParallaxX != 0 || gameObj.ParallaxY != 0 {
+			// parallax tiles aren't drawn where they are, snapshot.Visible works out when they are on screen
+			scenery.AddParallax(gameObj)
+			continue
+		}
+		scenery.Add(gameObj)
+	}
+	return scenery
+}
+
+// nextMap loads the next map in the rotation and moves everyone over to it.
+// If it doesn't load, because it was broken since the server started, the map after it is tried,
+// and if none of them load the current one is played again. The caller must hold WriteAccess.
+func nextMap() {
+	for range rotation {
+		entry := rotation[next]
+		next = (next + 1) % len(rotation)
+		l, err := loadLevel(entry.Map)
+		if err == nil {
+			err = enterLevel(l, entry)
+		}
+		if err == nil {
+			return
+		}
+		log.Printf("skipping %v: %v", entry.Map, err)
+	}
+	log.Printf("no map in the rotation loads, playing %v again", current.name)
+	err := enterLevel(current, currentEntry)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// enterLevel clears out the world and builds l in it. Players stay, everything else goes.
+// Every client gets a full snapshot of the new map with its next update. The caller must hold WriteAccess.
+func enterLevel(l *level, entry rotationEntry) error {
+	gm, waves, err := entry.settings(l)
+	if err != nil {
+		return err
+	}
+
+	kept := simulationObjects[:0]
+	for _, obj := range simulationObjects {
+		if _, ok := obj.(*player.NetworkPlayer); ok {
+			kept = append(kept, obj)
+			continue
+		}
+		gameObj := obj.GetObject()
+		if gameObj.Body != nil && physics.ContainsBody(gameObj.Body) {
+			physics.RemoveBody(gameObj.Body)
+		}
+		if gameObj.Shape != nil && physics.ContainsShape(gameObj.Shape) {
+			physics.RemoveShape(gameObj.Shape)
+		}
+	}
+	clear(simulationObjects[len(kept):])
+	simulationObjects = kept
+	if current != nil {
+		for _, shapes := range current.walls {
+			for _, shape := range shapes {
+				physics.RemoveShape(shape)
+			}
+		}
+	}
+
+	current, currentEntry = l, entry
+	playing.Store(l.name)
+	simulationObjects = append(simulationObjects, l.tiles...)
+	for _, shapes := range l.walls {
+		for _, shape := range shapes {
+			physics.AddShape(shape)
+		}
+	}
+	scenery = l.scenery
+	players.SpawnPoints = spawnPoints
+	if len(spawnPoints) == 0 {
+		// the command line wins over the map
+		players.SpawnPoints = l.objects.spawns
+	}
+	// enemies come from the map's own spawn points for them, or from the players' on maps without any
+	enemySpawns := l.objects.enemySpawns
+	if len(enemySpawns) == 0 {
+		enemySpawns = players.SpawnPoints
+	}
+	if len(enemySpawns) == 0 {
+		enemySpawns = player.DefaultSpawnPoints
+	}
+
+	match := &mode.Match{
+		Players:     players,
+		Spawn:       spawn,
+		TimeLimit:   *timeLimit,
+		ScoreLimit:  *scoreLimit,
+		Waves:       waves,
+		EnemySpawns: enemySpawns,
+		Triggers:    l.objects.triggers,
+		Placed:      l.objects.placed,
+		Map:         l.name,
+	}
+	if entry.TimeLimit != nil {
+		match.TimeLimit = *entry.TimeLimit
+	}
+	if entry.ScoreLimit != nil {
+		match.ScoreLimit = *entry.ScoreLimit
+	}
+	if len(rotation) > 1 || entry.Rounds > 0 {
+		match.Rounds = entry.Rounds
+		match.NextMap = rotation[next].Map
+	}
+	round = mode.NewRound(gm, match)
+	mapChanged = true
+	log.Printf("playing %v", l.name)
+	return nil
+}
@@ -0,0 +1,78 @@
+package message
+
+import "Geomyidae/internal/shared_structs"
+
+// Objects in the simulation talk to each other with messages. Anything can be a message, each kind is its own type,
+// and the receiver switches on the type. A message is addressed to one object by UUID, or to everyone.
+// Messages sent during a tick are delivered when the tick is over, and can be read all through the next tick.
+// Sending never blocks and nothing is dropped, however many messages an object gets in one tick.
+// Everything here is only used from the simulation, with the player List's WriteAccess held.
+
+// Everyone is the address of a message for every object
+const Everyone = ""
+
+// Message is anything sent between objects
+type Message any
+
+// Envelope is a message with who sent it, who it is for and when
+type Envelope struct {
+	Tick    uint64
+	From    string // UUID of the sender
+	To      string // UUID of the receiver, or Everyone
+	Message Message
+}
+
+// PickupGranted is sent to a player that collected a pickup
+type PickupGranted struct {
+	Kind string // like bombplus
+}
+
+// TriggerFired is sent to everyone when a player sets off a trigger tile
+type TriggerFired struct {
+	Player string // UUID of who set it off
+}
+
+var (
+	sent      []Envelope            // this tick, for the next one
+	delivered map[string][]Envelope // from the last tick, by who they are for
+	broadcast []Envelope            // from the last tick, for everyone
+)
+
+// Send sends msg from one object to another, by UUID
+func Send(tick shared_structs.Tick, from, to string, msg Message) {
+	sent = append(sent, Envelope{Tick: tick.Number, From: from, To: to, Message: msg})
+}
+
+// SendEveryone sends msg from an object to every object
+func SendEveryone(tick shared_structs.Tick, from string, msg Message) {
+	Send(tick, from, Everyone, msg)
+}
+
+// Read returns the messages delivered to the object with UUID to, oldest first. It doesn't include messages for everyone.
+func Read(to string) []Envelope {
+	return delivered[to]
+}
+
+// Broadcasts returns the messages delivered to everyone, oldest first
+func Broadcasts() []Envelope {
+	return broadcast
+}
+
+// Deliver makes everything sent since the last Deliver readable, and throws away what was delivered then.
+// The main loop calls it once at the end of every tick.
+func Deliver() {
+	clear(delivered)
+	if delivered == nil {
+		delivered = make(map[string][]Envelope)
+	}
+	broadcast = broadcast[:0]
+	for _, envelope := range sent {
+		if envelope.To == Everyone {
+			broadcast = append(broadcast, envelope)
+			continue
+		}
+		delivered[envelope.To] = append(delivered[envelope.To], envelope)
+	}
+	clear(sent)
+	sent = sent[:0]
+}
@@ -0,0 +1,84 @@
+package message
+
+import (
+	"Geomyidae/internal/shared_structs"
+	"slices"
+	"testing"
+)
+
+func TestDeliver(t *testing.T) {
+	Deliver() // start clean, whatever ran before
+	tick := shared_structs.Tick{Number: 7}
+	Send(tick, "turret", "alice", PickupGranted{Kind: "bombplus"})
+	SendEveryone(tick, "alice", TriggerFired{Player: "alice"})
+	Send(tick, "pickup", "alice", PickupGranted{Kind: "speed"})
+	Send(tick, "pickup", "bob", PickupGranted{Kind: "shield"})
+	SendEveryone(tick, "bob", TriggerFired{Player: "bob"})
+
+	if len(Read("alice")) != 0 || len(Broadcasts()) != 0 {
+		t.Fatal("messages can be read before they are delivered")
+	}
+	Deliver()
+
+	if got, want := kinds(Read("alice")), []string{"bombplus", "speed"}; !slices.Equal(got, want) {
+		t.Errorf("alice got %v, want %v", got, want)
+	}
+	if got, want := kinds(Read("bob")), []string{"shield"}; !slices.Equal(got, want) {
+		t.Errorf("bob got %v, want %v", got, want)
+	}
+	if got := Read("carol"); len(got) != 0 {
+		t.Errorf("carol got %v without being sent anything", got)
+	}
+	broadcasts := Broadcasts()
+	if len(broadcasts) != 2 || broadcasts[0].Message != (TriggerFired{Player: "alice"}) || broadcasts[1].Message != (TriggerFired{Player: "bob"}) {
+		t.Errorf("the broadcasts are %v", broadcasts)
+	}
+	envelope := Read("bob")[0]
+	if envelope.Tick != 7 || envelope.From != "pickup" || envelope.To != "bob" {
+		t.Errorf("bob's envelope is %+v", envelope)
+	}
+
+	// sent during the next tick, so only readable after the one after
+	Send(shared_structs.Tick{Number: 8}, "turret", "bob", PickupGranted{Kind: "bombplus"})
+	if got := kinds(Read("bob")); !slices.Equal(got, []string{"shield"}) {
+		t.Errorf("before the next Deliver bob has %v", got)
+	}
+	Deliver()
+	if got := kinds(Read("bob")); !slices.Equal(got, []string{"bombplus"}) {
+		t.Errorf("after the next Deliver bob has %v", got)
+	}
+	if got := Read("alice"); len(got) != 0 {
+		t.Errorf("alice still has %v", got)
+	}
+	if got := Broadcasts(); len(got) != 0 {
+		t.Errorf("the broadcasts are still %v", got)
+	}
+}
+
+// TestDeliverMany checks nothing is dropped however many messages one object gets in a tick
+func TestDeliverMany(t *testing.T) {
+	Deliver()
+	tick := shared_structs.Tick{Number: 1}
+	for i := range 1000 {
+		Send(tick, "", "alice", i)
+	}
+	Deliver()
+	got := Read("alice")
+	if len(got) != 1000 {
+		t.Fatalf("alice got %d messages", len(got))
+	}
+	for i, envelope := range got {
+		if envelope.Message != i {
+			t.Fatalf("message %d is %v", i, envelope.Message)
+		}
+	}
+}
+
+// kinds are the kinds of the PickupGranted messages in envelopes
+func kinds(envelopes []Envelope) []string {
+	var kinds []string
+	for _, envelope := range envelopes {
+		kinds = append(kinds, envelope.Message.(PickupGranted).Kind)
+	}
+	return kinds
+}
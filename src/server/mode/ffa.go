@@ -0,0 +1,99 @@
+package mode
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/combat"
+	"Geomyidae/server/player"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// FreeForAllScoreLimit is how many kills win a free-for-all round, unless the server sets a score limit
+const FreeForAllScoreLimit = 10
+
+// FreeForAll is every player for themselves. The first to the score limit wins, or whoever has the most kills when time runs out.
+type FreeForAll struct {
+	kills map[string]int
+}
+
+func (f *FreeForAll) Name() string {
+	return "ffa"
+}
+
+func (f *FreeForAll) Timed() bool {
+	return true
+}
+
+// Team leaves everyone off any team, so everyone can hurt everyone else
+func (f *FreeForAll) Team(m *Match, p *player.NetworkPlayer) constants.Team {
+	return ""
+}
+
+// SpawnPoint picks the spawn point furthest from everyone else
+func (f *FreeForAll) SpawnPoint(m *Match, p *player.NetworkPlayer, points []cp.Vector) cp.Vector {
+	return furthestFromEnemies(m, p, points)
+}
+
+func (f *FreeForAll) Start(m *Match) {
+	f.kills = make(map[string]int)
+}
+
+func (f *FreeForAll) Update(m *Match, tick shared_structs.Tick) (bool, string) {
+	leader, best := "", 0
+	for id, kills := range f.kills {
+		if kills > best || (kills == best && id < leader) {
+			leader, best = id, kills
+		}
+	}
+	if best >= m.scoreLimit(FreeForAllScoreLimit) {
+		return true, fmt.Sprintf("%v wins with %d kills", m.nameOf(leader), best)
+	}
+	if m.TimeUp() {
+		if best == 0 {
+			return true, "Time is up, nobody scored"
+		}
+		return true, fmt.Sprintf("Time is up, %v wins with %d kills", m.nameOf(leader), best)
+	}
+	return false, ""
+}
+
+func (f *FreeForAll) Killed(m *Match, kill combat.Kill) {
+	if kill.VictimType != constants.Player || kill.Killer == kill.Victim {
+		return
+	}
+	if _, ok := m.Players.Players[kill.Killer]; ok {
+		f.kills[kill.Killer]++
+	}
+}
+
+func (f *FreeForAll) TeamScores() []shared_structs.TeamScore {
+	return nil
+}
+
+// furthestFromEnemies picks the spawn point that is furthest from the closest living player not on p's team
+func furthestFromEnemies(m *Match, p *player.NetworkPlayer, points []cp.Vector) cp.Vector {
+	best, bestDistance := points[0], -1.0
+	for _, point := range points {
+		closest := -1.0
+		for _, other := range m.Players.Players {
+			if other == p || (other.Team != "" && other.Team == p.Team) || other.Dead() {
+				continue
+			}
+			distance := point.DistanceSq(other.Body.Position())
+			if closest < 0 || distance < closest {
+				closest = distance
+			}
+		}
+		if closest < 0 {
+			// nobody to get away from
+			return points[rand.IntN(len(points))]
+		}
+		if closest > bestDistance {
+			best, bestDistance = point, closest
+		}
+	}
+	return best
+}
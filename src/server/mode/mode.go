@@ -0,0 +1,104 @@
+package mode
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/combat"
+	"Geomyidae/server/director"
+	"Geomyidae/server/entity"
+	"Geomyidae/server/player"
+	"Geomyidae/server/tile"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// A game mode decides what players are trying to do: which team they are on, where they spawn, what spawns around them,
+// what counts for score and when a round is won. The main loop runs the mode through a Round.
+
+// GameMode is one way to play. Every method is called from the simulation with the player List's WriteAccess held.
+type GameMode interface {
+	// Name is what the mode is called on the command line, in map properties and on the client
+	Name() string
+	// Timed reports whether rounds have a time limit
+	Timed() bool
+	// Team picks the team of a player that just joined
+	Team(m *Match, p *player.NetworkPlayer) constants.Team
+	// SpawnPoint picks where a player appears, out of the configured spawn points
+	SpawnPoint(m *Match, p *player.NetworkPlayer, points []cp.Vector) cp.Vector
+	// Start sets up a new round. Anything the mode spawned last round should be cleaned up here.
+	Start(m *Match)
+	// Update runs every tick of a round, and returns the result once the round is over
+	Update(m *Match, tick shared_structs.Tick) (over bool, result string)
+	// Killed is told about every kill during a round
+	Killed(m *Match, kill combat.Kill)
+	// TeamScores are the scores of each team, for modes that keep them
+	TeamScores() []shared_structs.TeamScore
+}
+
+// Match is what a game mode gets to work with
+type Match struct {
+	Players *player.List
+	// Spawn adds an object to the world straight away
+	Spawn func(obj shared_structs.HasBehavior)
+	// TimeLimit is how long a round lasts in seconds, 0 for no limit. It only applies to timed modes.
+	TimeLimit float64
+	// ScoreLimit is the score that wins a round, 0 for the mode's default
+	ScoreLimit int
+	// Triggers and Placed, like pickups, are placed on the map in Tiled, for modes that use them
+	Triggers []tile.Trigger
+	Placed   []entity.Placement
+	// Waves is the wave file co-op plays, and EnemySpawns where its enemies appear, in meters
+	Waves       *director.Plan
+	EnemySpawns []cp.Vector
+	// Map is the name of the map being played, and NextMap the one played once Rounds rounds are over.
+	// With Rounds 0, rounds go on forever on the same map.
+	Map     string
+	NextMap string
+	Rounds  int
+	// Elapsed is how many seconds the current round has been going
+	Elapsed float64
+}
+
+// TimeUp reports whether the round has run out of time
+func (m *Match) TimeUp() bool {
+	return m.TimeLimit > 0 && m.Elapsed >= m.TimeLimit
+}
+
+// scoreLimit returns the configured score limit, or def if there isn't one
+func (m *Match) scoreLimit(def int) int {
+	if m.ScoreLimit > 0 {
+		return m.ScoreLimit
+	}
+	return def
+}
+
+// nameOf returns the name of the player with this UUID, if they are still around
+func (m *Match) nameOf(id string) string {
+	if p, ok := m.Players.Players[id]; ok {
+		return p.Name
+	}
+	return "someone"
+}
+
+// Modes are all the game modes, by name
+var Modes = map[string]func() GameMode{
+	"sandbox": func() GameMode { return &Sandbox{} },
+	"ffa":     func() GameMode { return &FreeForAll{} },
+	"tdm":     func() GameMode { return &TeamDeathmatch{} },
+	"coop":    func() GameMode { return &Survival{} },
+}
+
+// DefaultMode is played if neither the command line nor the map picks one
+const DefaultMode = "sandbox"
+
+// New returns the mode called name
+func New(name string) (GameMode, error) {
+	newMode, ok := Modes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown game mode %q, pick one of %v", name, slices.Sorted(maps.Keys(Modes)))
+	}
+	return newMode(), nil
+}
@@ -0,0 +1,115 @@
+package mode
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/combat"
+	"Geomyidae/server/player"
+	"log"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// Intermission is how many seconds the result of a round is shown before the next one starts
+const Intermission = 10.0
+
+// Round runs a game mode round after round
+type Round struct {
+	Mode   GameMode
+	match  *Match
+	number int
+	over   bool
+	result string
+	// seconds until the next round, once this one is over
+	nextRound float64
+	finished  bool
+}
+
+// NewRound hooks mode up to the players and starts the first round. Anyone already playing, from a mode on the map
+// before, is put on a team again and respawned. The caller must hold the List's WriteAccess.
+func NewRound(mode GameMode, match *Match) *Round {
+	match.Players.TeamFor = func(p *player.NetworkPlayer) constants.Team {
+		return mode.Team(match, p)
+	}
+	match.Players.SpawnFor = func(p *player.NetworkPlayer, points []cp.Vector) cp.Vector {
+		return mode.SpawnPoint(match, p, points)
+	}
+	match.Players.AssignTeams()
+	match.Players.ResetRound()
+	r := &Round{Mode: mode, match: match}
+	r.start()
+	return r
+}
+
+func (r *Round) start() {
+	r.number++
+	r.over = false
+	r.result = ""
+	r.match.Elapsed = 0
+	r.Mode.Start(r.match)
+	log.Printf("%v round %d started", r.Mode.Name(), r.number)
+}
+
+// Update runs the mode for one tick. Once a round is over, it waits out the intermission and starts the next one.
+// The caller must hold the List's WriteAccess.
+func (r *Round) Update(tick shared_structs.Tick) {
+	if r.finished {
+		return
+	}
+	if r.over {
+		r.nextRound -= tick.DeltaTime
+		if r.nextRound <= 0 && r.last() {
+			r.finished = true
+			log.Printf("%v is finished after %d rounds", r.match.Map, r.number)
+		} else if r.nextRound <= 0 {
+			r.match.Players.ResetRound()
+			r.start()
+		}
+		return
+	}
+	r.match.Elapsed += tick.DeltaTime
+	r.over, r.result = r.Mode.Update(r.match, tick)
+	if r.over {
+		r.nextRound = Intermission
+		log.Printf("%v round %d over: %v", r.Mode.Name(), r.number, r.result)
+	}
+}
+
+// last reports whether this is the last round before the map changes
+func (r *Round) last() bool {
+	return r.match.Rounds > 0 && r.number >= r.match.Rounds
+}
+
+// Finished reports whether the last round is over, intermission and all. Nothing happens after that,
+// the caller is expected to move on to the next map with a new Round.
+func (r *Round) Finished() bool {
+	return r.finished
+}
+
+// Killed passes a kill on to the mode, unless the round is already over. The caller must hold the List's WriteAccess.
+func (r *Round) Killed(kill combat.Kill) {
+	if !r.over {
+		r.Mode.Killed(r.match, kill)
+	}
+}
+
+// State is how the round is going, for the clients
+func (r *Round) State() *shared_structs.MatchState {
+	state := &shared_structs.MatchState{
+		Mode:   r.Mode.Name(),
+		Map:    r.match.Map,
+		Round:  r.number,
+		Teams:  r.Mode.TeamScores(),
+		Result: r.result,
+	}
+	if r.Mode.Timed() && r.match.TimeLimit > 0 && !r.over {
+		state.TimeLeft = shared_structs.RoundedFloat2(max(r.match.TimeLimit-r.match.Elapsed, 0))
+	}
+	if r.over {
+		state.NextRound = shared_structs.RoundedFloat2(max(r.nextRound, 0))
+		if r.last() {
+			state.NextMap = r.match.NextMap
+		}
+	}
+	return state
+}
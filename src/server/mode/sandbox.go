@@ -0,0 +1,65 @@
+package mode
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/combat"
+	"Geomyidae/server/player"
+	"Geomyidae/server/tile"
+	"log"
+	"math/rand/v2"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// Sandbox is the game as it was before there were modes: the map's triggers, which set off turrets when they are
+// touched, and everything else placed on it, like pickups. Nobody wins and the round never ends.
+type Sandbox struct {
+	started bool
+}
+
+func (s *Sandbox) Name() string {
+	return "sandbox"
+}
+
+func (s *Sandbox) Timed() bool {
+	return false
+}
+
+func (s *Sandbox) Team(m *Match, p *player.NetworkPlayer) constants.Team {
+	return combat.TeamPlayers
+}
+
+func (s *Sandbox) SpawnPoint(m *Match, p *player.NetworkPlayer, points []cp.Vector) cp.Vector {
+	return points[rand.IntN(len(points))]
+}
+
+func (s *Sandbox) Start(m *Match) {
+	// there is only ever one round
+	if s.started {
+		return
+	}
+	s.started = true
+
+	for _, trigger := range m.Triggers {
+		m.Spawn(tile.NewTrigger(trigger))
+	}
+	for _, placed := range m.Placed {
+		obj, err := placed.Spawn()
+		if err != nil {
+			log.Printf("not spawning a %v from the map: %v", placed.Type, err)
+			continue
+		}
+		m.Spawn(obj)
+	}
+}
+
+func (s *Sandbox) Update(m *Match, tick shared_structs.Tick) (bool, string) {
+	return false, ""
+}
+
+func (s *Sandbox) Killed(m *Match, kill combat.Kill) {}
+
+func (s *Sandbox) TeamScores() []shared_structs.TeamScore {
+	return nil
+}
@@ -0,0 +1,77 @@
+package mode
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/combat"
+	"Geomyidae/server/director"
+	"Geomyidae/server/player"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// Survival is co-op against waves of turrets and trackers, spawned by a director following the Match's wave file.
+// Clear every wave to win, the round is lost if every player is dead at the same time.
+type Survival struct {
+	director *director.Director
+}
+
+func (s *Survival) Name() string {
+	return "coop"
+}
+
+func (s *Survival) Timed() bool {
+	return false
+}
+
+func (s *Survival) Team(m *Match, p *player.NetworkPlayer) constants.Team {
+	return combat.TeamPlayers
+}
+
+func (s *Survival) SpawnPoint(m *Match, p *player.NetworkPlayer, points []cp.Vector) cp.Vector {
+	return points[rand.IntN(len(points))]
+}
+
+func (s *Survival) Start(m *Match) {
+	if s.director == nil {
+		s.director = director.New(m.Waves, m.EnemySpawns)
+		return
+	}
+	s.director.Reset()
+}
+
+func (s *Survival) Update(m *Match, tick shared_structs.Tick) (bool, string) {
+	var targets []*shared_structs.GameObject
+	players := 0
+	for _, p := range m.Players.Players {
+		players++
+		if !p.Dead() && !p.Delete {
+			targets = append(targets, p.GameObject)
+		}
+	}
+	if players > 0 && len(targets) == 0 {
+		return true, fmt.Sprintf("Everyone was destroyed on wave %d", s.director.Wave())
+	}
+
+	s.director.Update(tick, targets, m.Spawn)
+	if s.director.Done() {
+		return true, fmt.Sprintf("Survived all %d waves", s.director.Cleared())
+	}
+	return false, ""
+}
+
+// Killed lets the director know when a player dies, so it can ease off, and when an enemy does, so the wave can be cleared
+func (s *Survival) Killed(m *Match, kill combat.Kill) {
+	if kill.VictimType == constants.Player {
+		s.director.PlayerDied()
+		return
+	}
+	s.director.Killed(kill.Victim)
+}
+
+// TeamScores is how many waves the players have cleared
+func (s *Survival) TeamScores() []shared_structs.TeamScore {
+	return []shared_structs.TeamScore{{Team: string(combat.TeamPlayers), Score: s.director.Cleared()}}
+}
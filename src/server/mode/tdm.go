@@ -0,0 +1,102 @@
+package mode
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/combat"
+	"Geomyidae/server/player"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// TeamDeathmatchScoreLimit is how many kills win a team deathmatch round, unless the server sets a score limit
+const TeamDeathmatchScoreLimit = 25
+
+const (
+	TeamRed  constants.Team = "red"
+	TeamBlue constants.Team = "blue"
+)
+
+// TeamDeathmatch is red against blue. Every kill of the other team scores for your team.
+type TeamDeathmatch struct {
+	scores map[constants.Team]int
+}
+
+func (t *TeamDeathmatch) Name() string {
+	return "tdm"
+}
+
+func (t *TeamDeathmatch) Timed() bool {
+	return true
+}
+
+// Team puts new players on whichever team is smaller
+func (t *TeamDeathmatch) Team(m *Match, p *player.NetworkPlayer) constants.Team {
+	count := map[constants.Team]int{}
+	for _, other := range m.Players.Players {
+		if other != p && !other.Delete {
+			count[other.Team]++
+		}
+	}
+	if count[TeamBlue] < count[TeamRed] {
+		return TeamBlue
+	}
+	return TeamRed
+}
+
+// SpawnPoint gives each team half of the spawn points, red the first half and blue the second
+func (t *TeamDeathmatch) SpawnPoint(m *Match, p *player.NetworkPlayer, points []cp.Vector) cp.Vector {
+	if len(points) >= 2 {
+		half := len(points) / 2
+		if p.Team == TeamBlue {
+			points = points[half:]
+		} else {
+			points = points[:half]
+		}
+	}
+	return points[rand.IntN(len(points))]
+}
+
+func (t *TeamDeathmatch) Start(m *Match) {
+	t.scores = map[constants.Team]int{TeamRed: 0, TeamBlue: 0}
+}
+
+func (t *TeamDeathmatch) Update(m *Match, tick shared_structs.Tick) (bool, string) {
+	red, blue := t.scores[TeamRed], t.scores[TeamBlue]
+	limit := m.scoreLimit(TeamDeathmatchScoreLimit)
+	switch {
+	case red >= limit:
+		return true, fmt.Sprintf("Red team wins %d to %d", red, blue)
+	case blue >= limit:
+		return true, fmt.Sprintf("Blue team wins %d to %d", blue, red)
+	case !m.TimeUp():
+		return false, ""
+	case red > blue:
+		return true, fmt.Sprintf("Time is up, red team wins %d to %d", red, blue)
+	case blue > red:
+		return true, fmt.Sprintf("Time is up, blue team wins %d to %d", blue, red)
+	default:
+		return true, fmt.Sprintf("Time is up, it's a draw at %d each", red)
+	}
+}
+
+func (t *TeamDeathmatch) Killed(m *Match, kill combat.Kill) {
+	if kill.VictimType != constants.Player {
+		return
+	}
+	killer, ok := m.Players.Players[kill.Killer]
+	victim, ok2 := m.Players.Players[kill.Victim]
+	if !ok || !ok2 || killer.Team == victim.Team {
+		return
+	}
+	t.scores[killer.Team]++
+}
+
+func (t *TeamDeathmatch) TeamScores() []shared_structs.TeamScore {
+	return []shared_structs.TeamScore{
+		{Team: string(TeamRed), Score: t.scores[TeamRed]},
+		{Team: string(TeamBlue), Score: t.scores[TeamBlue]},
+	}
+}
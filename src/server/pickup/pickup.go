@@ -3,9 +3,12 @@ package pickup
 import (
 	"Geomyidae/internal/constants"
 	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/collision"
+	"Geomyidae/server/entity"
+	"Geomyidae/server/message"
+	"errors"
 
 	"github.com/google/uuid"
-	"github.com/jakecoffman/cp/v2"
 )
 
 type Pickup struct {
@@ -13,46 +16,32 @@ type Pickup struct {
 	pickupType string
 }
 
-func (p *Pickup) ApplyBehavior(deltaTime float64, spawnerPipeline chan shared_structs.HasBehavior) {
-	p.Body.EachArbiter(func(arbiter *cp.Arbiter) {
-		_, bodB := arbiter.Bodies()
-		if ptr, ok := bodB.UserData.(*shared_structs.GameObject); ok {
-			if ptr.Identity == constants.Player {
-				p.Delete = true
-				// if the channel is initialized and can accept a value currently, send bombplus
-				if ptr.Inbox != nil {
-					select {
-					case ptr.Inbox <- p.pickupType:
-					default:
-					}
-				}
-			}
-		}
-	})
+func (p *Pickup) ApplyBehavior(tick shared_structs.Tick, spawnerPipeline chan shared_structs.HasBehavior) {
+}
+
+// Collected is the collision rule for a player touching a pickup. Only the first player to touch it gets it.
+func Collected(c collision.Contact) {
+	p := c.EntityA.(*Pickup)
+	if p.Delete {
+		return
+	}
+	p.Delete = true
+	message.Send(c.Tick, p.UUID, c.B.UUID, message.PickupGranted{Kind: p.pickupType})
 }
 
 func NewPickup(x float64, y float64, str string) *Pickup {
-	body := cp.NewBody(1, 1)
-	shape := cp.NewBox(body, 1, 1, 0)
-	shape.SetElasticity(0.25)
-	shape.SetDensity(0.5)
-	shape.SetFriction(1.0)
-	body.AddShape(shape)
-	body.SetPosition(cp.Vector{X: x, Y: y})
+	archetype := entity.ArchetypeOf(string(constants.Pickup))
+	body, shape := archetype.NewBody(x, y)
 	gameObject := shared_structs.GameObject{
-		X:             int(x),
-		Y:             int(y),
-		Sprite:        "spaceShooterRedux",
-		SpriteOffsetX: 320,
-		SpriteOffsetY: 310,
-		SpriteWidth:   16,
-		SpriteHeight:  16,
-		UUID:          uuid.New().String(),
-		Body:          body,
-		Shape:         shape,
-		IsStatic:      false,
-		Identity:      constants.Pickup,
+		X:        int(x),
+		Y:        int(y),
+		UUID:     uuid.New().String(),
+		Body:     body,
+		Shape:    shape,
+		IsStatic: false,
+		Identity: constants.Pickup,
 	}
+	archetype.Dress(&gameObject)
 	body.UserData = &gameObject
 
 	return &Pickup{
@@ -64,3 +53,14 @@ func NewPickup(x float64, y float64, str string) *Pickup {
 func (p *Pickup) GetObject() *shared_structs.GameObject {
 	return p.GameObject
 }
+
+// The kind of a pickup spawned by name is its pickup property
+func init() {
+	entity.Register(constants.Pickup, func(params entity.Params) (shared_structs.HasBehavior, error) {
+		kind := params.Properties["pickup"]
+		if kind == "" {
+			return nil, errors.New("it needs a pickup property saying what kind it is")
+		}
+		return NewPickup(params.X, params.Y, kind), nil
+	})
+}
@@ -0,0 +1,110 @@
+package player
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/collision"
+	"Geomyidae/server/combat"
+	"math/rand/v2"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// Players have hit points. Anything with Damage set hurts a player when it touches them, if the combat rules allow it.
+// At zero hit points the ship drops out of the world, and comes back at a spawn point after RespawnTime seconds.
+
+// MaxHealth is how many hit points a ship starts with
+const MaxHealth = 100
+
+// DefaultRespawnTime is how long a dead player waits to respawn, in seconds
+const DefaultRespawnTime = 3.0
+
+// HazardInterval is how many seconds apart a ship resting on a hazard tile takes its damage, instead of every tick
+const HazardInterval = 0.5
+
+// DefaultSpawnPoints are where players appear if no spawn points are configured, in meters
+var DefaultSpawnPoints = []cp.Vector{{X: 5, Y: 5}}
+
+// Dead reports whether the player is waiting to respawn
+func (p *NetworkPlayer) Dead() bool {
+	return p.health <= 0
+}
+
+// Health returns the player's hit points and how long until they respawn, for the owning client's HUD
+func (p *NetworkPlayer) Health() (health int, respawnIn float64) {
+	return p.health, max(p.respawnIn, 0)
+}
+
+// Hurt is the collision rule for a ship touching anything with Damage set.
+// It runs every step they touch, so a ship resting on a hazard tile keeps taking damage.
+func Hurt(c collision.Contact) {
+	p := c.EntityA.(*NetworkPlayer)
+	ptr := c.B
+	if ptr.Damage <= 0 || p.Dead() || !c.Rules.CanHurt(ptr, p.GameObject) {
+		return
+	}
+	if ptr.Identity == constants.Tile {
+		// a sleeping body's contacts aren't solved, so a ship resting on a hazard would stop getting hurt
+		p.Body.Activate()
+		if p.hazardTime > 0 {
+			return
+		}
+		p.hazardTime = HazardInterval
+	}
+	p.health -= ptr.Damage
+	p.attackers.Hit(ptr, c.Tick)
+	combat.ReportHit(ptr, p.GameObject, c.Tick)
+	if p.Dead() {
+		p.attackers.Killed(p.GameObject, ptr, c.Tick)
+		p.die()
+	}
+}
+
+// die takes the ship out of the world until it respawns.
+// Without collisions it can't be hit, and it isn't found by anyone's area of interest, so it vanishes from every screen.
+func (p *NetworkPlayer) die() {
+	p.health = 0
+	p.respawnIn = p.list.RespawnTime
+	p.Body.SetVelocity(0, 0)
+	p.Body.SetAngularVelocity(0)
+	p.Shape.SetFilter(cp.SHAPE_FILTER_NONE)
+}
+
+// waitToRespawn counts down the respawn timer, and brings the ship back at a spawn point once it runs out
+func (p *NetworkPlayer) waitToRespawn(deltaTime float64) {
+	p.respawnIn -= deltaTime
+	if p.respawnIn > 0 {
+		return
+	}
+	p.spawn()
+}
+
+// spawn puts the ship at a spawn point with full health
+func (p *NetworkPlayer) spawn() {
+	spawns := p.list.SpawnPoints
+	if len(spawns) == 0 {
+		spawns = DefaultSpawnPoints
+	}
+	p.health = MaxHealth
+	p.respawnIn = 0
+	if p.list.SpawnFor != nil {
+		p.Body.SetPosition(p.list.SpawnFor(p, spawns))
+	} else {
+		p.Body.SetPosition(spawns[rand.IntN(len(spawns))])
+	}
+	p.Body.SetVelocity(0, 0)
+	p.Body.SetAngle(0)
+	p.Body.SetAngularVelocity(0)
+	p.Shape.SetFilter(cp.SHAPE_FILTER_ALL)
+	p.Body.Activate()
+}
+
+// ResetRound respawns every player with a clean slate for a new round. The caller must hold WriteAccess.
+func (l *List) ResetRound() {
+	for _, p := range l.Players {
+		p.stats = shared_structs.PlayerScore{}
+		p.attackers = combat.Attackers{}
+		p.bombCount = p.archetype.Bombs
+		p.spawn()
+	}
+}
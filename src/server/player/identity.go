@@ -0,0 +1,106 @@
+package player
+
+import (
+	"Geomyidae/internal/ship"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the longest display name allowed, in characters
+const MaxNameLength = 16
+
+var (
+	ErrNameEmpty   = errors.New("name is empty")
+	ErrNameTooLong = errors.New("name is too long")
+	ErrNameInvalid = errors.New("name can only have letters, numbers, spaces, - and _")
+	ErrNameTaken   = errors.New("name is taken")
+	ErrUnknownSkin = errors.New("unknown ship skin")
+)
+
+// CleanName trims name and checks it is fit to show above a ship
+func CleanName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", ErrNameEmpty
+	}
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return "", ErrNameTooLong
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ' ' && r != '-' && r != '_' {
+			return "", ErrNameInvalid
+		}
+	}
+	return name, nil
+}
+
+// CheckSkin returns the skin called name, or the default if name is empty
+func CheckSkin(name string) (ship.Skin, error) {
+	if name == "" {
+		name = ship.DefaultSkin
+	}
+	skin, ok := ship.Skins[name]
+	if !ok {
+		return ship.Skin{}, ErrUnknownSkin
+	}
+	return skin, nil
+}
+
+// NameTaken reports whether another player in the world already goes by name. The caller must hold WriteAccess.
+func (l *List) NameTaken(name string, except *NetworkPlayer) bool {
+	for _, p := range l.Players {
+		if p != except && !p.Delete && strings.EqualFold(p.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Join is how a player gets into the world once their hello checks out.
+// If session belongs to a ship that is waiting for its player, they get that ship back, otherwise they get a new one.
+// The name and skin must already be clean.
+func (l *List) Join(session, name string, skin ship.Skin) (*NetworkPlayer, error) {
+	l.WriteAccess.Lock()
+	defer l.WriteAccess.Unlock()
+	p := l.findSession(session)
+	if l.NameTaken(name, p) {
+		return nil, ErrNameTaken
+	}
+	if p == nil {
+		p = l.newNetworkPlayer()
+		p.setIdentity(name, skin)
+		if l.TeamFor != nil {
+			p.Team = l.TeamFor(p)
+		}
+		p.spawn()
+	} else {
+		p.reconnect()
+		p.setIdentity(name, skin)
+	}
+	return p, nil
+}
+
+// AssignTeams puts everyone on a team again with TeamFor, one at a time, for when the game mode changes.
+// The caller must hold WriteAccess.
+func (l *List) AssignTeams() {
+	if l.TeamFor == nil {
+		return
+	}
+	for _, p := range l.Players {
+		p.Team = ""
+	}
+	for _, p := range l.Players {
+		p.Team = l.TeamFor(p)
+	}
+}
+
+func (p *NetworkPlayer) setIdentity(name string, skin ship.Skin) {
+	p.Name = name
+	p.Sprite = skin.Sprite
+	p.SpriteOffsetX = skin.X
+	p.SpriteOffsetY = skin.Y
+	p.SpriteWidth = skin.Width
+	p.SpriteHeight = skin.Height
+}
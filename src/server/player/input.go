@@ -0,0 +1,54 @@
+package player
+
+import (
+	"Geomyidae/internal/shared_structs"
+)
+
+// Input commands arrive from the socket goroutine whenever the network delivers them, and are used up one per tick.
+// Commands the player already has (resent for safety, or arriving out of order) are dropped, as are commands that
+// are too old to matter.
+
+// the most commands that can be waiting. If more pile up, the oldest are skipped so the player doesn't fall behind.
+const maxQueuedInputs = 8
+
+// if a command was sampled this many ticks before the server's current tick, it is dropped as stale
+const staleInputTicks = 50
+
+// if no commands arrive for this many ticks, the player lets go of everything,
+// so a dropped connection doesn't leave a ship thrusting forever
+const inputTimeoutTicks = 10
+
+// QueueInputs queues commands the player hasn't seen yet. The caller must hold the List's WriteAccess.
+func (p *NetworkPlayer) QueueInputs(commands []shared_structs.InputCommand) {
+	for _, cmd := range commands {
+		if cmd.Seq <= p.queuedSeq {
+			continue // duplicate or out of order
+		}
+		if cmd.Tick+staleInputTicks < p.tick {
+			continue // stale
+		}
+		p.inputs = append(p.inputs, cmd)
+		p.queuedSeq = cmd.Seq
+	}
+	if len(p.inputs) > maxQueuedInputs {
+		p.inputs = p.inputs[len(p.inputs)-maxQueuedInputs:]
+	}
+}
+
+// nextInput uses up the next queued command and returns the actions to apply this tick
+func (p *NetworkPlayer) nextInput(tick shared_structs.Tick) shared_structs.Actions {
+	p.tick = tick.Number
+	if len(p.inputs) == 0 {
+		p.idleTicks++
+		if p.idleTicks > inputTimeoutTicks {
+			p.actions = 0
+		}
+		return p.actions
+	}
+	cmd := p.inputs[0]
+	p.inputs = p.inputs[1:]
+	p.idleTicks = 0
+	p.actions = cmd.Actions
+	p.appliedSeq = cmd.Seq
+	return p.actions
+}
@@ -3,9 +3,12 @@ package player
 import (
 	"Geomyidae/internal/constants"
 	"Geomyidae/internal/shared_structs"
+	"Geomyidae/internal/ship"
 	"Geomyidae/server/bomb"
 	"Geomyidae/server/bullet"
-	"math"
+	"Geomyidae/server/combat"
+	"Geomyidae/server/entity"
+	"Geomyidae/server/message"
 	"sync"
 
 	"github.com/google/uuid"
@@ -17,38 +20,60 @@ type List struct {
 	Physics     *cp.Space
 	WriteAccess sync.Mutex
 	apOb        func(networkPlayer *NetworkPlayer)
+	sessions    map[string]*NetworkPlayer // by session token
+	// GracePeriod is how many seconds a disconnected player's ship waits for them to reconnect
+	GracePeriod float64
+	// RespawnTime is how many seconds a dead player waits to respawn
+	RespawnTime float64
+	// SpawnPoints are where players appear, in meters. DefaultSpawnPoints is used if there are none.
+	SpawnPoints []cp.Vector
+	// TeamFor and SpawnFor let the game mode pick a new player's team and where a player spawns.
+	// They are called with WriteAccess held. If they are nil, everyone is on combat.TeamPlayers and spawns at random.
+	TeamFor  func(p *NetworkPlayer) constants.Team
+	SpawnFor func(p *NetworkPlayer, points []cp.Vector) cp.Vector
 }
 
 func NewList(physics *cp.Space, fn func(networkPlayer *NetworkPlayer)) *List {
 	players := make(map[string]*NetworkPlayer)
-	return &List{Players: players, WriteAccess: sync.Mutex{}, Physics: physics, apOb: fn}
+	return &List{Players: players, WriteAccess: sync.Mutex{}, Physics: physics, apOb: fn, sessions: make(map[string]*NetworkPlayer), GracePeriod: DefaultGracePeriod, RespawnTime: DefaultRespawnTime}
 }
 
 type NetworkPlayer struct {
 	*shared_structs.GameObject
 
+	// Session is the secret token the player uses to get this ship back after a disconnect
+	Session         string
+	connections     int
+	disconnectedFor float64
+	list            *List
+
+	health    int
+	respawnIn float64 // seconds until a dead player respawns
+	attackers combat.Attackers
+	stats     shared_structs.PlayerScore // see stats.go
+
 	canJump              bool
-	HeldKeys             []string
+	inputs               []shared_structs.InputCommand // waiting to be applied, oldest first
+	queuedSeq            uint64                        // the newest command ever queued
+	appliedSeq           uint64                        // the command applied in the last tick
+	actions              shared_structs.Actions        // the actions applied in the last tick
+	idleTicks            int                           // ticks since the last command was applied
+	tick                 uint64
 	shootTime            float64
 	bombCount            int
 	bombTime             float64
 	portalToggleCooldown float64
+	hazardTime           float64 // seconds until a hazard tile can hurt the ship again
+	archetype            entity.Archetype
 }
 
-// NewNetworkPlayer creates a network player and stores a pointer to it in both the master list and the network player list
-// both values are the same pointer, it does not matter which you use, but you cannot reassign the pointer later
-func (l *List) NewNetworkPlayer() *NetworkPlayer {
-	l.WriteAccess.Lock()
-	defer l.WriteAccess.Unlock()
+// newNetworkPlayer creates a network player and stores a pointer to it in both the master list and the network player list
+// both values are the same pointer, it does not matter which you use, but you cannot reassign the pointer later.
+// The caller must hold WriteAccess, players are created through Join.
+func (l *List) newNetworkPlayer() *NetworkPlayer {
 	name := uuid.New().String()
 
-	body := cp.NewBody(1, 1)
-	shape := cp.NewBox(body, 1, 1, 0)
-	shape.SetElasticity(0.25)
-	shape.SetDensity(0.5)
-	shape.SetFriction(1.0)
-	body.AddShape(shape)
-	body.SetPosition(cp.Vector{X: 5, Y: 5})
+	body, shape := ship.NewBody()
 
 	l.Physics.AddShape(shape)
 	l.Physics.AddBody(body)
@@ -62,32 +87,38 @@ func (l *List) NewNetworkPlayer() *NetworkPlayer {
 		SpriteOffsetY: 0,
 		SpriteWidth:   98,
 		SpriteHeight:  75,
-		NeedsStatics:  true,
 		Identity:      constants.Player,
-		Inbox:         make(chan string, 10), // if the inbox fills up it will block so the sender is responsible for not sending data once it is full
 		Portal:        true,
-	}, HeldKeys: []string{}, canJump: true}
+		Team:          combat.TeamPlayers,
+	}, canJump: true, Session: newSession(), connections: 1, list: l}
 	body.UserData = player.GameObject
 	point := &player
 	l.apOb(point)
 	l.Players[name] = point
+	l.sessions[player.Session] = point
 
-	player.bombCount = 1
+	player.archetype = entity.ArchetypeOf(string(constants.Player))
+	player.bombCount = player.archetype.Bombs
 	return &player
 }
 
-// these could be multiplied by delta time
-const thrust = 2
-const maxSpeed = 25.0
-const turn = 2
-
-func (p *NetworkPlayer) ApplyBehavior(deltaTime float64, spawnerPipeline chan shared_structs.HasBehavior) {
-	tr := thrust * deltaTime
-	tn := turn * deltaTime
-	x, y := p.Body.Velocity().X, p.Body.Velocity().Y
-	if math.Abs(x)+math.Abs(y) > maxSpeed {
-		p.Body.SetVelocityVector(p.Body.Velocity().Mult(0.95))
+// The player archetype has cooldowns and how many bombs a ship starts with. Its looks come from its skin,
+// and how it flies is in the ship package, which the client shares to predict its own ship.
+func init() {
+	entity.Requires(string(constants.Player), false)
+}
+
+func (p *NetworkPlayer) ApplyBehavior(tick shared_structs.Tick, spawnerPipeline chan shared_structs.HasBehavior) {
+	deltaTime := tick.DeltaTime
+	p.waitForReconnect(deltaTime, p.list.GracePeriod)
+	actions := p.nextInput(tick)
+	if p.Dead() {
+		// inputs are still used up, so nothing is saved up for after the respawn
+		p.waitToRespawn(deltaTime)
+		return
 	}
+	p.stats.TimeAlive += shared_structs.RoundedFloat2(deltaTime)
+	ship.Fly(p.Body, ship.ControlsFor(actions), deltaTime)
 	if p.shootTime >= 0 {
 		p.shootTime -= deltaTime
 	}
@@ -97,62 +128,58 @@ func (p *NetworkPlayer) ApplyBehavior(deltaTime float64, spawnerPipeline chan sh
 	if p.portalToggleCooldown >= 0 {
 		p.portalToggleCooldown -= deltaTime
 	}
-	select {
-	case msg, ok := <-p.Inbox:
-		if ok {
-			if msg == "bombplus" {
+	if p.hazardTime >= 0 {
+		p.hazardTime -= deltaTime
+	}
+	for _, envelope := range message.Read(p.UUID) {
+		switch msg := envelope.Message.(type) {
+		case message.PickupGranted:
+			if msg.Kind == "bombplus" {
 				p.bombCount++
 			}
+			p.stats.Pickups++
 		}
-	default:
 	}
-	for _, key := range p.HeldKeys {
-		if key == "B" && p.bombCount > 0 && p.bombTime <= 0 {
-			p.bombCount--
-			newBomb := bomb.NewBomb(float64(p.X), float64(p.Y))
-			select {
-			case spawnerPipeline <- newBomb:
-			default:
-			}
-			p.bombTime = 0.5
-		}
-		if key == "W" {
-			p.Body.ApplyImpulseAtLocalPoint(cp.Vector{
-				X: -math.Sin(tr),
-				Y: -math.Cos(-tr),
-			}, cp.Vector{X: 0, Y: 0})
-		}
-		if key == "A" {
-			rot := p.Body.Angle()
-			p.Body.SetAngle(rot - tn)
-			p.Body.SetAngularVelocity(0)
-		}
-		if key == "D" {
-			rot := p.Body.Angle()
-			p.Body.SetAngle(rot + tn)
-			p.Body.SetAngularVelocity(0)
+	if actions.Has(shared_structs.ActionBomb) && p.bombCount > 0 && p.bombTime <= 0 {
+		p.bombCount--
+		newBomb := bomb.NewBomb(p.GameObject, float64(p.X), float64(p.Y))
+		select {
+		case spawnerPipeline <- newBomb:
+		default:
 		}
-		if key == "S" {
-			p.Body.SetVelocityVector(p.Body.Velocity().Mult(0.95))
-			p.Body.SetAngularVelocity(p.Body.AngularVelocity() * 0.75)
-		}
-		if key == "E" && p.shootTime <= 0 {
-			p.shootTime = 0.5 // seconds
-			newBullet := bullet.NewBullet(p.GetObject())
-			select {
-			case spawnerPipeline <- newBullet:
-			default:
-			}
-		}
-		if key == "P" && p.portalToggleCooldown <= 0 {
-			p.Portal = !p.Portal
-			p.portalToggleCooldown = 0.5
+		p.bombTime = p.archetype.BombCooldown
+	}
+	if actions.Has(shared_structs.ActionFire) && p.shootTime <= 0 {
+		p.shootTime = p.archetype.Cooldown
+		newBullet := bullet.NewBullet(p.GetObject())
+		select {
+		case spawnerPipeline <- newBullet:
+			p.stats.ShotsFired++
+		default:
 		}
 	}
+	if actions.Has(shared_structs.ActionPortal) && p.portalToggleCooldown <= 0 {
+		p.Portal = !p.Portal
+		p.portalToggleCooldown = p.archetype.PortalCooldown
+	}
 
 	return
 }
 
+// ShipState is the exact state of the ship after the last tick, for the owning client's prediction
+func (p *NetworkPlayer) ShipState() shared_structs.ShipState {
+	pos, vel := p.Body.Position(), p.Body.Velocity()
+	return shared_structs.ShipState{
+		Seq:             p.appliedSeq,
+		X:               pos.X,
+		Y:               pos.Y,
+		VX:              vel.X,
+		VY:              vel.Y,
+		Angle:           p.Body.Angle(),
+		AngularVelocity: p.Body.AngularVelocity(),
+	}
+}
+
 func (p *NetworkPlayer) GetObject() *shared_structs.GameObject {
 	return p.GameObject
 }
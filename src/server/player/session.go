@@ -0,0 +1,62 @@
+package player
+
+import (
+	"github.com/google/uuid"
+)
+
+// When a player's connection drops, their ship stays in the world for GracePeriod seconds.
+// If they reconnect with their session token in that time, they get the same ship back.
+
+// DefaultGracePeriod is how long a disconnected ship waits for its player, in seconds
+const DefaultGracePeriod = 30.0
+
+// findSession returns the player that owns session, or nil if there is no such session or it has already expired.
+// The caller must hold WriteAccess.
+func (l *List) findSession(session string) *NetworkPlayer {
+	p, ok := l.sessions[session]
+	if !ok || p.Delete {
+		return nil
+	}
+	return p
+}
+
+// reconnect marks the player connected again
+func (p *NetworkPlayer) reconnect() {
+	p.connections++
+	p.disconnectedFor = 0
+	p.inputs = nil
+}
+
+// Disconnect marks that one of the player's connections has dropped.
+// The ship is only left waiting once every connection is gone, since a reconnect can beat the old connection timing out.
+func (l *List) Disconnect(p *NetworkPlayer) {
+	l.WriteAccess.Lock()
+	defer l.WriteAccess.Unlock()
+	if p.connections > 0 {
+		p.connections--
+	}
+}
+
+// Remove forgets a player that has been deleted from the world. The caller must hold WriteAccess.
+func (l *List) Remove(name string) {
+	if p, ok := l.Players[name]; ok {
+		delete(l.sessions, p.Session)
+	}
+	delete(l.Players, name)
+}
+
+func newSession() string {
+	return uuid.New().String()
+}
+
+// waitForReconnect counts down the grace period while the player is disconnected,
+// and deletes the ship once it runs out
+func (p *NetworkPlayer) waitForReconnect(deltaTime, gracePeriod float64) {
+	if p.connections > 0 {
+		return
+	}
+	p.disconnectedFor += deltaTime
+	if p.disconnectedFor > gracePeriod {
+		p.Delete = true
+	}
+}
@@ -0,0 +1,68 @@
+package player
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/combat"
+	"cmp"
+	"slices"
+)
+
+// Stats are kept for the whole time a player is in the world, including across reconnects.
+// Some are counted by the player itself, the rest come from the combat events the main loop passes to Credit.
+
+// Credit counts a kill towards everyone involved. The caller must hold WriteAccess.
+func (l *List) Credit(kill combat.Kill) {
+	if kill.VictimType == constants.Player {
+		if victim, ok := l.Players[kill.Victim]; ok {
+			victim.stats.Deaths++
+		}
+	}
+	if killer, ok := l.Players[kill.Killer]; ok && kill.Killer != kill.Victim {
+		switch kill.VictimType {
+		case constants.Player:
+			killer.stats.Kills++
+		case constants.Turret:
+			killer.stats.Turrets++
+		}
+	}
+	for _, id := range kill.Assists {
+		if assist, ok := l.Players[id]; ok {
+			assist.stats.Assists++
+		}
+	}
+}
+
+// CreditHit counts a hit towards the player that landed it. Only bullets count, since only bullets count as shots fired,
+// so accuracy never goes over 100%. The caller must hold WriteAccess.
+func (l *List) CreditHit(hit combat.Hit) {
+	if hit.Weapon != constants.Bullet {
+		return
+	}
+	if attacker, ok := l.Players[hit.Attacker]; ok && hit.Attacker != hit.Victim {
+		attacker.stats.ShotsHit++
+	}
+}
+
+// Scoreboard returns everyone's stats, best first
+func (l *List) Scoreboard() []shared_structs.PlayerScore {
+	l.WriteAccess.Lock()
+	defer l.WriteAccess.Unlock()
+	scores := make([]shared_structs.PlayerScore, 0, len(l.Players))
+	for _, p := range l.Players {
+		score := p.stats
+		score.ID = p.UUID
+		score.Name = p.Name
+		score.Team = string(p.Team)
+		scores = append(scores, score)
+	}
+	slices.SortFunc(scores, func(a, b shared_structs.PlayerScore) int {
+		return cmp.Or(
+			cmp.Compare(b.Kills, a.Kills),
+			cmp.Compare(b.Turrets, a.Turrets),
+			cmp.Compare(a.Deaths, b.Deaths),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+	return scores
+}
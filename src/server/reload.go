@@ -0,0 +1,193 @@
+package main
+
+import (
+	"Geomyidae/internal/shared_structs"
+	"flag"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// With -watch, the map being played is reloaded whenever anything in the maps directory changes, for designers iterating
+// on a level. The new map is diffed against the running one: tiles and walls that didn't change are left alone,
+// and everything else is added or removed, so clients only get sent what changed. Players keep playing throughout.
+// Tiles, walls and spawn points are reloaded. Triggers, pickups and the map's mode only change when the map is next
+// loaded in the rotation.
+
+var watchMaps = flag.Bool("watch", false, "reload the map when it changes on disk, for development. Maps are read from "+embeddedMaps+" on disk if -maps isn't set")
+
+// watchInterval is how often the maps directory is checked for changes
+const watchInterval = 500 * time.Millisecond
+
+// playing is the name of the map being played, for the watcher
+var playing atomic.Value
+
+// reloads are maps that changed on disk, loaded and ready to be swapped in by the simulation
+var reloads = make(chan *level, 1)
+
+// watch checks dir for changes every watchInterval, and reloads the map being played when there are any.
+// A map that doesn't load is logged and left alone, the old one stays until it is fixed.
+func watch(dir string) {
+	last := lastModified(dir)
+	for range time.Tick(watchInterval) {
+		modified := lastModified(dir)
+		if !modified.After(last) {
+			continue
+		}
+		last = modified
+		name, ok := playing.Load().(string)
+		if !ok {
+			continue // nothing is being played yet
+		}
+		l, err := loadLevel(name)
+		if err != nil {
+			log.Printf("not reloading %v: %v", name, err)
+			continue
+		}
+		select {
+		case reloads <- l:
+		default:
+			log.Printf("not reloading %v, the last reload hasn't been swapped in yet", name)
+		}
+	}
+}
+
+// lastModified is the latest modification time of anything in dir
+func lastModified(dir string) time.Time {
+	var latest time.Time
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // it is checked again next time
+		}
+		info, err := entry.Info()
+		if err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest
+}
+
+// applyReload swaps in a reload of the map being played, if there is one. The caller must hold WriteAccess.
+func applyReload() {
+	var l *level
+	select {
+	case l = <-reloads:
+	default:
+		return
+	}
+	if l.name != current.name {
+		return // the map changed since, and the new one was loaded fresh anyway
+	}
+
+	// tiles that look the same in the same place are kept, so clients don't get sent them again
+	unchanged := make(map[tileKey][]shared_structs.HasBehavior)
+	for i, obj := range current.tiles {
+		unchanged[current.keys[i]] = append(unchanged[current.keys[i]], obj)
+	}
+	kept := make(map[*shared_structs.GameObject]bool)
+	added := 0
+	for i, obj := range l.tiles {
+		key := l.keys[i]
+		for j, old := range unchanged[key] {
+			if sameTile(old.GetObject(), obj.GetObject()) {
+				l.tiles[i] = old
+				kept[old.GetObject()] = true
+				unchanged[key] = append(unchanged[key][:j], unchanged[key][j+1:]...)
+				break
+			}
+		}
+		if !kept[l.tiles[i].GetObject()] {
+			added++
+			simulationObjects = append(simulationObjects, obj)
+		}
+	}
+	removed := make(map[*shared_structs.GameObject]bool)
+	for _, obj := range current.tiles {
+		if !kept[obj.GetObject()] {
+			removed[obj.GetObject()] = true
+		}
+	}
+	remaining := simulationObjects[:0]
+	for _, obj := range simulationObjects {
+		if !removed[obj.GetObject()] {
+			remaining = append(remaining, obj)
+		}
+	}
+	clear(simulationObjects[len(remaining):])
+	simulationObjects = remaining
+	l.scenery = newScenery(l.tiles)
+
+	// the same goes for walls
+	wallsChanged := 0
+	keptWalls := make(map[string]bool)
+	for key, shapes := range current.walls {
+		if len(l.walls[key]) == len(shapes) {
+			l.walls[key] = shapes
+			keptWalls[key] = true
+			continue
+		}
+		for _, shape := range shapes {
+			physics.RemoveShape(shape)
+		}
+		wallsChanged++
+	}
+	for key, shapes := range l.walls {
+		if keptWalls[key] {
+			continue
+		}
+		for _, shape := range shapes {
+			physics.AddShape(shape)
+		}
+		wallsChanged++
+	}
+
+	current = l
+	scenery = l.scenery
+	if len(spawnPoints) == 0 {
+		players.SpawnPoints = l.objects.spawns
+	}
+	log.Printf("reloaded %v: %d tiles added, %d removed, %d walls changed", l.name, added, len(removed), wallsChanged)
+}
+
+// sameTile reports whether two tiles look the same in the same place
+func sameTile(a, b *shared_structs.GameObject) bool {
+	return a.Sprite == b.Sprite &&
+		a.SpriteOffsetX == b.SpriteOffsetX &&
+		a.SpriteOffsetY == b.SpriteOffsetY &&
+		a.SpriteWidth == b.SpriteWidth &&
+		a.SpriteHeight == b.SpriteHeight &&
+		a.SpriteFlipHorizontal == b.SpriteFlipHorizontal &&
+		a.SpriteFlipVertical == b.SpriteFlipVertical &&
+		a.SpriteFlipDiagonal == b.SpriteFlipDiagonal &&
+		a.Depth == b.Depth &&
+		a.ParallaxX == b.ParallaxX &&
+		a.ParallaxY == b.ParallaxY &&
+		a.Body.Position() == b.Body.Position()
+}
+
+// watchFromDisk makes maps load from disk if -watch is set, since they have to come from disk to be edited.
+// It is called before any map is loaded.
+func watchFromDisk() {
+	if !*watchMaps {
+		return
+	}
+	if *mapDir == "" {
+		*mapDir = embeddedMaps
+	}
+	if _, err := os.Stat(*mapDir); err != nil {
+		log.Fatalf("-watch needs the maps on disk: %v", err)
+	}
+}
+
+// startWatching starts the watcher if -watch is set. It is called once the first map is being played.
+func startWatching() {
+	if !*watchMaps {
+		return
+	}
+	log.Printf("watching %v for changes", *mapDir)
+	go watch(*mapDir)
+}
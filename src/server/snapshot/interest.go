@@ -0,0 +1,52 @@
+package snapshot
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// Margin is how far past the edge of the screen, in meters, objects are still sent.
+// Without it, fast objects would pop into view instead of flying in from off screen.
+const Margin = 4.0
+
+// Visible returns the part of world that a player centered at center can see.
+// Objects that drop out of view are deleted on the client, the same as objects that are destroyed.
+// Everything that moves is found in the physics space, and everything that doesn't in scenery.
+func Visible(space *cp.Space, scenery *Scenery, center cp.Vector, world Snapshot) Snapshot {
+	halfWidth := float64(constants.ScreenWidth)/constants.MetersToPixels/2 + Margin
+	halfHeight := float64(constants.ScreenHeight)/constants.MetersToPixels/2 + Margin
+	view := make(Snapshot)
+
+	// the client's camera is the top left corner of the screen, and parallax objects are drawn nearer to it
+	camera := cp.Vector{
+		X: center.X - float64(constants.ScreenWidth)/constants.MetersToPixels/2,
+		Y: center.Y - float64(constants.ScreenHeight)/constants.MetersToPixels/2,
+	}
+	for _, gameObj := range scenery.parallax {
+		pos := gameObj.Body.Position()
+		drawnX := pos.X + camera.X*float64(gameObj.ParallaxX)
+		drawnY := pos.Y + camera.Y*float64(gameObj.ParallaxY)
+		if drawnX < center.X-halfWidth || drawnX > center.X+halfWidth || drawnY < center.Y-halfHeight || drawnY > center.Y+halfHeight {
+			continue
+		}
+		if obj, ok := world[gameObj.UUID]; ok {
+			view[gameObj.UUID] = obj
+		}
+	}
+
+	found := func(shape *cp.Shape, data any) {
+		gameObj, ok := shape.Body().UserData.(*shared_structs.GameObject)
+		if !ok {
+			return
+		}
+		if obj, ok := world[gameObj.UUID]; ok {
+			view[gameObj.UUID] = obj
+		}
+	}
+	bounds := cp.NewBBForExtents(center, halfWidth, halfHeight)
+	space.BBQuery(bounds, cp.SHAPE_FILTER_ALL, found, nil)
+	scenery.index.BBQuery(bounds, cp.SHAPE_FILTER_ALL, found, nil)
+	return view
+}
@@ -0,0 +1,33 @@
+package snapshot
+
+import (
+	"Geomyidae/internal/shared_structs"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// Scenery is what Visible finds without the physics space: tiles, whose collisions are merged into bigger shapes,
+// and parallax tiles, which aren't drawn where they are.
+type Scenery struct {
+	// index is a space that is never stepped, only queried, with a 1x1 box for every tile
+	index    *cp.Space
+	parallax []*shared_structs.GameObject
+}
+
+func NewScenery() *Scenery {
+	index := cp.NewSpace()
+	index.UseSpatialHash(1, 1000)
+	return &Scenery{index: index}
+}
+
+// Add adds a tile where its body is
+func (s *Scenery) Add(obj *shared_structs.GameObject) {
+	shape := cp.NewBox(obj.Body, 1, 1, 0)
+	obj.Body.AddShape(shape)
+	s.index.AddShape(shape)
+}
+
+// AddParallax adds a tile on a parallax layer
+func (s *Scenery) AddParallax(obj *shared_structs.GameObject) {
+	s.parallax = append(s.parallax, obj)
+}
@@ -0,0 +1,99 @@
+package snapshot
+
+import (
+	"Geomyidae/internal/shared_structs"
+	"sync"
+)
+
+// Snapshot is the state of every visible object at one tick, keyed by UUID.
+// The objects are copies and must not be changed once captured. Objects that didn't change
+// between two snapshots share the same pointer, so comparing snapshots is mostly pointer comparisons.
+type Snapshot map[string]*shared_structs.GameObject
+
+// Capture copies the state of objects into a new Snapshot, reusing the copies in prev for anything that didn't change
+func Capture(prev Snapshot, objects []shared_structs.HasBehavior) Snapshot {
+	snap := make(Snapshot, len(prev))
+	for _, obj := range objects {
+		gameObj := obj.GetObject()
+		if gameObj.Body == nil || gameObj.Delete {
+			continue // nothing to draw
+		}
+		if old, ok := prev[gameObj.UUID]; ok && gameObj.Diff(old) == 0 {
+			snap[gameObj.UUID] = old
+			continue
+		}
+		wire := gameObj.Wire()
+		snap[gameObj.UUID] = &wire
+	}
+	return snap
+}
+
+// how many snapshots are remembered per client.
+// If a client takes longer than this many broadcasts to acknowledge one, it gets a full snapshot instead.
+const historySize = 64
+
+type sent struct {
+	tick     uint64
+	snapshot Snapshot
+}
+
+// History is the snapshots recently sent to one client, and the last one it acknowledged
+type History struct {
+	mu    sync.Mutex
+	acked uint64
+	sent  [historySize]sent
+}
+
+// Ack records that the client has received the snapshot for tick. It is safe to call from the socket goroutines.
+func (h *History) Ack(tick uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if tick > h.acked {
+		h.acked = tick
+	}
+}
+
+// Reset forgets the acknowledged snapshot, so the next Delta is a full snapshot
+func (h *History) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.acked = 0
+}
+
+// Delta compares current to the last snapshot the client acknowledged, and returns the baseline tick it was compared to
+// along with the objects that changed. Anything that is gone since the baseline is included with Delete set.
+// If there is no usable baseline, the baseline is 0 and every object is included in full.
+func (h *History) Delta(tick uint64, current Snapshot) (uint64, []shared_structs.GameObject) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sent[tick%historySize] = sent{tick: tick, snapshot: current}
+
+	var objects []shared_structs.GameObject
+	base := h.sent[h.acked%historySize]
+	if h.acked == 0 || base.tick != h.acked {
+		for _, obj := range current {
+			objects = append(objects, *obj)
+		}
+		return 0, objects
+	}
+
+	for id, obj := range current {
+		old, ok := base.snapshot[id]
+		if !ok {
+			objects = append(objects, *obj)
+			continue
+		}
+		if old == obj {
+			continue
+		}
+		if mask := obj.Diff(old); mask != 0 {
+			objects = append(objects, obj.Delta(mask))
+		}
+	}
+	for id := range base.snapshot {
+		if _, ok := current[id]; !ok {
+			objects = append(objects, shared_structs.GameObject{UUID: id, Delete: true})
+		}
+	}
+	return h.acked, objects
+}
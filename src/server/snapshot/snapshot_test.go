@@ -0,0 +1,45 @@
+package snapshot
+
+import (
+	"Geomyidae/internal/shared_structs"
+	"testing"
+)
+
+func TestDelta(t *testing.T) {
+	ship := &shared_structs.GameObject{UUID: "ship", X: 1}
+	rock := &shared_structs.GameObject{UUID: "rock", X: 5}
+	moved := &shared_structs.GameObject{UUID: "ship", X: 2}
+	h := &History{}
+
+	if baseline, objects := h.Delta(1, Snapshot{"ship": ship, "rock": rock}); baseline != 0 || len(objects) != 2 {
+		t.Fatalf("with nothing acknowledged got baseline %d and %d objects", baseline, len(objects))
+	}
+	h.Ack(1)
+	baseline, objects := h.Delta(2, Snapshot{"ship": moved})
+	if baseline != 1 || len(objects) != 2 {
+		t.Fatalf("from tick 1 got baseline %d and %v", baseline, objects)
+	}
+	for _, obj := range objects {
+		switch {
+		case obj.UUID == "ship" && obj.X != 2:
+			t.Errorf("the ship is at %d", obj.X)
+		case obj.UUID == "rock" && !obj.Delete:
+			t.Error("the rock that is gone isn't deleted")
+		}
+	}
+
+	// the client said it doesn't have tick 1 after all
+	h.Reset()
+	if baseline, objects := h.Delta(3, Snapshot{"ship": moved}); baseline != 0 || len(objects) != 1 || objects[0].Delete {
+		t.Errorf("after a reset got baseline %d and %v", baseline, objects)
+	}
+
+	// an acknowledged tick that has been overwritten in the history can't be a baseline either
+	h.Ack(3)
+	for tick := uint64(4); tick < 4+historySize; tick++ {
+		h.Delta(tick, Snapshot{"ship": moved})
+	}
+	if baseline, _ := h.Delta(4+historySize, Snapshot{"ship": moved}); baseline != 0 {
+		t.Errorf("from a forgotten tick got baseline %d", baseline)
+	}
+}
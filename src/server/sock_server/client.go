@@ -1,10 +1,14 @@
 package sock_server
 
 import (
+	"Geomyidae/internal/constants"
 	"Geomyidae/internal/shared_structs"
+	"Geomyidae/internal/wire"
 	"Geomyidae/server/player"
+	"Geomyidae/server/snapshot"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
@@ -24,6 +28,9 @@ const (
 
 	// Maximum message size allowed from peer.
 	maxMessageSize = 512
+
+	// Time allowed for the client to say hello after connecting.
+	helloWait = 10 * time.Second
 )
 
 var (
@@ -34,6 +41,8 @@ var (
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	// Clients that don't ask for a protocol get JSON
+	Subprotocols: []string{wire.BinaryProtocol, wire.JSONProtocol},
 	CheckOrigin: func(r *http.Request) bool {
 		// Bypass all origin checks
 		// TODO: Not good for production use
@@ -51,6 +60,32 @@ type Client struct {
 	// Buffered channel of outbound messages.
 	Send   chan []byte
 	Player *player.NetworkPlayer
+
+	// History is the snapshots sent to this client, so the next one can be sent as a delta
+	History snapshot.History
+
+	// binary is true if the client asked for the binary protocol instead of JSON
+	binary bool
+}
+
+// Queue sends msg to the client if there is room, and reports whether there was.
+// A client that can't keep up misses snapshots, it doesn't hold up everybody else.
+func (c *Client) Queue(msg []byte) bool {
+	select {
+	case c.Send <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// Encode returns data in whichever protocol the client asked for
+func (c *Client) Encode(data *shared_structs.WorldData) []byte {
+	if c.binary {
+		return wire.Encode(data)
+	}
+	msg, _ := json.Marshal(data)
+	return msg
 }
 
 // readPump pumps messages from the websocket connection to the hub.
@@ -75,9 +110,22 @@ func (c *Client) readPump() {
 			break
 		}
 		message = bytes.TrimSpace(bytes.Replace(message, newline, space, -1))
-		keys := shared_structs.KeyStruct{}
-		err = json.Unmarshal(message, &keys)
-		c.Player.HeldKeys = keys.Keys
+		msg := shared_structs.ClientMessage{}
+		err = json.Unmarshal(message, &msg)
+		if err != nil {
+			log.Printf("error: %v", err)
+			continue
+		}
+		switch msg.Type {
+		case constants.InputMessage:
+			c.hub.playerList.WriteAccess.Lock()
+			c.Player.QueueInputs(msg.Inputs)
+			c.hub.playerList.WriteAccess.Unlock()
+		case constants.AckMessage:
+			c.History.Ack(msg.Ack)
+		case constants.ResyncMessage:
+			c.History.Reset()
+		}
 	}
 }
 
@@ -102,20 +150,12 @@ func (c *Client) writePump() {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			// Add queued chat messages to the current websocket message.
-			n := len(c.Send)
-			for i := 0; i < n; i++ {
-				w.Write(newline)
-				w.Write(<-c.Send)
+			// Each snapshot goes out as its own websocket message, since a delta can't be decoded if it is glued to another one.
+			messageType := websocket.TextMessage
+			if c.binary {
+				messageType = websocket.BinaryMessage
 			}
-
-			if err := w.Close(); err != nil {
+			if err := c.conn.WriteMessage(messageType, message); err != nil {
 				return
 			}
 		case <-ticker.C:
@@ -134,11 +174,59 @@ func serveWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
 		log.Println(err)
 		return
 	}
-	client := &Client{hub: hub, conn: conn, Send: make(chan []byte, 256)}
-	client.hub.register <- client
+	client := &Client{hub: hub, conn: conn, Send: make(chan []byte, 256), binary: conn.Subprotocol() == wire.BinaryProtocol}
 
 	// Allow collection of memory referenced by the caller by doing all work in
 	// new goroutines.
-	go client.writePump()
-	go client.readPump()
+	go client.handshake(r.URL.Query().Get("session"))
+}
+
+// handshake waits for the client's hello, and only spawns the player and starts the pumps if it checks out.
+// Otherwise the connection is closed with a reason the client can show.
+func (c *Client) handshake(session string) {
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(helloWait))
+	_, message, err := c.conn.ReadMessage()
+	if err != nil {
+		c.conn.Close()
+		return
+	}
+	msg := shared_structs.ClientMessage{}
+	err = json.Unmarshal(message, &msg)
+	if err != nil || msg.Type != constants.HelloMessage || msg.Hello == nil {
+		c.reject(constants.CloseBadHello, "expected a hello")
+		return
+	}
+	hello := msg.Hello
+	if hello.Version != wire.Version {
+		c.reject(constants.CloseBadVersion, fmt.Sprintf("server speaks protocol version %d, client speaks %d", wire.Version, hello.Version))
+		return
+	}
+	name, err := player.CleanName(hello.Name)
+	if err != nil {
+		c.reject(constants.CloseBadHello, err.Error())
+		return
+	}
+	skin, err := player.CheckSkin(hello.Skin)
+	if err != nil {
+		c.reject(constants.CloseBadHello, err.Error())
+		return
+	}
+	// the player has to exist before readPump starts handing it messages
+	c.Player, err = c.hub.playerList.Join(session, name, skin)
+	if err != nil {
+		c.reject(constants.CloseBadHello, err.Error())
+		return
+	}
+	c.hub.register <- c
+
+	go c.writePump()
+	go c.readPump()
+}
+
+// reject closes a connection that failed the handshake
+func (c *Client) reject(code int, reason string) {
+	log.Printf("turned away %v: %v", c.conn.RemoteAddr(), reason)
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
+	c.conn.Close()
 }
@@ -2,13 +2,15 @@ package sock_server
 
 import (
 	"Geomyidae/server/player"
+	"sync"
 )
 
 // Hub maintains the set of active Clients and broadcasts messages to the
 // Clients.
 type Hub struct {
-	// Registered Clients.
+	// Registered Clients. Use EachClient to read it from outside the hub.
 	Clients map[*Client]bool
+	mu      sync.Mutex
 
 	// Inbound messages from the Clients.
 	Broadcast chan []byte
@@ -32,27 +34,61 @@ func newHub(list *player.List) *Hub {
 	}
 }
 
+// EachClient calls fn for every registered client. Clients can't be registered or unregistered until it returns.
+func (h *Hub) EachClient(fn func(client *Client)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.Clients {
+		fn(client)
+	}
+}
+
 func (h *Hub) run() {
 	for {
 		select {
 		case client := <-h.register:
-			client.Player = h.playerList.NewNetworkPlayer()
+			h.mu.Lock()
 			h.Clients[client] = true
+			h.mu.Unlock()
 		case client := <-h.unregister:
-			if _, ok := h.Clients[client]; ok {
-				client.Player.Delete = true
-				delete(h.Clients, client)
-				close(client.Send)
+			h.mu.Lock()
+			ok := h.remove(client)
+			h.mu.Unlock()
+			if ok {
+				h.disconnect(client)
 			}
 		case message := <-h.Broadcast:
+			var slow []*Client
+			h.mu.Lock()
 			for client := range h.Clients {
 				select {
 				case client.Send <- message:
 				default:
-					close(client.Send)
-					delete(h.Clients, client)
+					h.remove(client)
+					slow = append(slow, client)
 				}
 			}
+			h.mu.Unlock()
+			for _, client := range slow {
+				h.disconnect(client)
+			}
 		}
 	}
 }
+
+// remove takes client out of the hub and closes its Send channel, which closes the connection.
+// It reports whether the client was still there. The caller must hold the hub's lock.
+func (h *Hub) remove(client *Client) bool {
+	if _, ok := h.Clients[client]; !ok {
+		return false
+	}
+	delete(h.Clients, client)
+	close(client.Send)
+	return true
+}
+
+// disconnect lets the player list know client has gone, however it went, so its ship waits a while in case the player
+// reconnects. The main loop takes the hub's lock with WriteAccess held, so this can't be called with the hub's lock held.
+func (h *Hub) disconnect(client *Client) {
+	h.playerList.Disconnect(client.Player)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// spawnList is a flag that collects spawn points given as x,y
+type spawnList []cp.Vector
+
+func (s *spawnList) String() string {
+	var points []string
+	for _, point := range *s {
+		points = append(points, fmt.Sprintf("%v,%v", point.X, point.Y))
+	}
+	return strings.Join(points, " ")
+}
+
+func (s *spawnList) Set(value string) error {
+	xs, ys, ok := strings.Cut(value, ",")
+	if !ok {
+		return fmt.Errorf("spawn point %q should look like x,y", value)
+	}
+	x, err := strconv.ParseFloat(strings.TrimSpace(xs), 64)
+	if err != nil {
+		return fmt.Errorf("spawn point %q: %w", value, err)
+	}
+	y, err := strconv.ParseFloat(strings.TrimSpace(ys), 64)
+	if err != nil {
+		return fmt.Errorf("spawn point %q: %w", value, err)
+	}
+	*s = append(*s, cp.Vector{X: x, Y: y})
+	return nil
+}
@@ -0,0 +1,99 @@
+package tile
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+// Solid tiles are made of a material, set with custom properties on the tile in the tileset:
+//   - solid: false makes the tile decoration, even on a collision layer
+//   - elasticity: how bouncy it is, 0.25 by default
+//   - friction: how grippy it is, 1 by default. Use something like 0.1 for ice.
+//   - damage: makes it a hazard, taking this many hit points from a ship every player.HazardInterval it touches it
+//   - oneway: up, down, left or right, the side that is solid. Things pass through from every other side.
+//
+// Tiles are only merged with neighbours of the same material.
+
+// Material is what a solid tile is like to bump into
+type Material struct {
+	Elasticity float64
+	Friction   float64
+	Damage     int
+	// OneWay points out of the solid side of a one-way tile, it is zero for tiles that are solid from every side
+	OneWay cp.Vector
+}
+
+// DefaultMaterial is what tiles are made of if their properties don't say otherwise
+var DefaultMaterial = Material{Elasticity: 0.25, Friction: 1.0}
+
+// oneWayDirections are the values of the oneway property. Y is down on the server, like in Tiled.
+var oneWayDirections = map[string]cp.Vector{
+	"up":    {X: 0, Y: -1},
+	"down":  {X: 0, Y: 1},
+	"left":  {X: -1, Y: 0},
+	"right": {X: 1, Y: 0},
+}
+
+// NewMaterial reads a tile's material from its properties. solid is false if the tile isn't solid at all.
+func NewMaterial(properties map[string]string) (material Material, solid bool, err error) {
+	material = DefaultMaterial
+	solid = true
+	for name, value := range properties {
+		switch name {
+		case "solid":
+			solid, err = strconv.ParseBool(value)
+		case "elasticity":
+			material.Elasticity, err = strconv.ParseFloat(value, 64)
+		case "friction":
+			material.Friction, err = strconv.ParseFloat(value, 64)
+		case "damage":
+			material.Damage, err = strconv.Atoi(value)
+		case "oneway":
+			var ok bool
+			material.OneWay, ok = oneWayDirections[value]
+			if !ok {
+				err = fmt.Errorf("it can be up, down, left or right")
+			}
+		default:
+			continue // properties for something else
+		}
+		if err != nil {
+			return material, solid, fmt.Errorf("property %v is %q: %w", name, value, err)
+		}
+	}
+	if material.Elasticity < 0 || material.Friction < 0 || material.Damage < 0 {
+		return material, solid, fmt.Errorf("elasticity, friction and damage can't be negative")
+	}
+	return material, solid, nil
+}
+
+// OneWayCollisionType is the collision type of one-way tiles, see HandleOneWay
+const OneWayCollisionType cp.CollisionType = 1
+
+// apply makes shape out of the material
+func (m Material) apply(shape *cp.Shape) {
+	shape.SetElasticity(m.Elasticity)
+	shape.SetDensity(0.5)
+	shape.SetFriction(m.Friction)
+	if m.OneWay != (cp.Vector{}) {
+		shape.SetCollisionType(OneWayCollisionType)
+		shape.UserData = m.OneWay
+	}
+}
+
+// HandleOneWay makes one-way tiles in space let things through, unless they come from the solid side.
+// A contact is ignored until the two shapes separate, so something halfway through a platform carries on through it.
+func HandleOneWay(space *cp.Space) {
+	handler := space.NewWildcardCollisionHandler(OneWayCollisionType)
+	handler.PreSolveFunc = func(arb *cp.Arbiter, space *cp.Space, userData interface{}) bool {
+		// the one-way tile is always the first shape, and the normal points from it to the other one
+		tile, _ := arb.Shapes()
+		solidSide, ok := tile.UserData.(cp.Vector)
+		if ok && arb.Normal().Dot(solidSide) < 0 {
+			return arb.Ignore()
+		}
+		return true
+	}
+}
@@ -0,0 +1,97 @@
+package tile
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"cmp"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jakecoffman/cp/v2"
+)
+
+// Solid tiles don't each get their own collision shape. Neighbouring tiles are merged into rectangles first,
+// so a big map has a few hundred static shapes in the physics space instead of tens of thousands.
+// The tiles keep their own sprites, they just aren't what things bump into.
+
+// Cell is a tile's place on the grid
+type Cell struct {
+	Col int
+	Row int
+}
+
+// Rect is Width by Height cells, from Col, Row in the top left
+type Rect struct {
+	Col    int
+	Row    int
+	Width  int
+	Height int
+}
+
+// Merge covers cells with rectangles, greedily: each rectangle starts from the top left cell that isn't covered yet,
+// is made as wide as it can be, then as tall as it can be at that width.
+// It isn't always the fewest rectangles possible, but it is close and it is fast.
+func Merge(cells []Cell) []Rect {
+	solid := make(map[Cell]bool, len(cells))
+	for _, cell := range cells {
+		solid[cell] = true
+	}
+	ordered := slices.Clone(cells)
+	slices.SortFunc(ordered, func(a, b Cell) int {
+		return cmp.Or(cmp.Compare(a.Row, b.Row), cmp.Compare(a.Col, b.Col))
+	})
+
+	var rects []Rect
+	for _, start := range ordered {
+		if !solid[start] {
+			continue // already covered
+		}
+		width := 1
+		for solid[Cell{start.Col + width, start.Row}] {
+			width++
+		}
+		height := 1
+		for rowIsSolid(solid, start.Col, start.Row+height, width) {
+			height++
+		}
+		for row := start.Row; row < start.Row+height; row++ {
+			for col := start.Col; col < start.Col+width; col++ {
+				delete(solid, Cell{col, row})
+			}
+		}
+		rects = append(rects, Rect{Col: start.Col, Row: start.Row, Width: width, Height: height})
+	}
+	return rects
+}
+
+func rowIsSolid(solid map[Cell]bool, col int, row int, width int) bool {
+	for c := col; c < col+width; c++ {
+		if !solid[Cell{c, row}] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewWall makes the collision shape for a merged rectangle of tiles made of material, offset by offset meters.
+// It is added straight to the physics space, it has nothing to draw and no behavior.
+func NewWall(rect Rect, offset cp.Vector, material Material) *shared_structs.GameObject {
+	body := cp.NewStaticBody()
+	shape := cp.NewBox(body, float64(rect.Width), float64(rect.Height), 0)
+	material.apply(shape)
+	body.AddShape(shape)
+	body.SetPosition(cp.Vector{
+		X: float64(rect.Col) + float64(rect.Width)/2 + offset.X,
+		Y: float64(rect.Row) + float64(rect.Height)/2 + offset.Y,
+	})
+	wall := &shared_structs.GameObject{
+		UUID:     uuid.New().String(),
+		Body:     body,
+		Shape:    shape,
+		IsStatic: true,
+		Identity: constants.Tile,
+		Damage:   material.Damage,
+	}
+	body.UserData = wall
+	return wall
+}
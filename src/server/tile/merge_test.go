@@ -0,0 +1,190 @@
+package tile
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name  string
+		cells []Cell
+		want  []Rect
+	}{
+		{name: "nothing"},
+		{
+			name:  "single cell",
+			cells: []Cell{{Col: 3, Row: 4}},
+			want:  []Rect{{Col: 3, Row: 4, Width: 1, Height: 1}},
+		},
+		{
+			name:  "row",
+			cells: []Cell{{2, 0}, {0, 0}, {1, 0}},
+			want:  []Rect{{Col: 0, Row: 0, Width: 3, Height: 1}},
+		},
+		{
+			name:  "square",
+			cells: []Cell{{0, 0}, {1, 0}, {0, 1}, {1, 1}},
+			want:  []Rect{{Col: 0, Row: 0, Width: 2, Height: 2}},
+		},
+		{
+			// a column down the left, and a foot along the bottom
+			name:  "L-shape",
+			cells: []Cell{{0, 0}, {0, 1}, {0, 2}, {1, 2}, {2, 2}},
+			want: []Rect{
+				{Col: 0, Row: 0, Width: 1, Height: 3},
+				{Col: 1, Row: 2, Width: 2, Height: 1},
+			},
+		},
+		{
+			// a 3 by 3 block with the middle missing
+			name:  "hole",
+			cells: []Cell{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {2, 1}, {0, 2}, {1, 2}, {2, 2}},
+			want: []Rect{
+				{Col: 0, Row: 0, Width: 3, Height: 1},
+				{Col: 0, Row: 1, Width: 1, Height: 2},
+				{Col: 2, Row: 1, Width: 1, Height: 2},
+				{Col: 1, Row: 2, Width: 1, Height: 1},
+			},
+		},
+		{
+			name:  "apart",
+			cells: []Cell{{0, 0}, {5, 5}, {-2, 5}},
+			want: []Rect{
+				{Col: 0, Row: 0, Width: 1, Height: 1},
+				{Col: -2, Row: 5, Width: 1, Height: 1},
+				{Col: 5, Row: 5, Width: 1, Height: 1},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Merge(test.cells)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("Merge(%v) = %v, want %v", test.cells, got, test.want)
+			}
+			checkCovered(t, test.cells, got)
+		})
+	}
+}
+
+// TestMergeCave checks a big generated map, where the rectangles aren't worked out by hand
+func TestMergeCave(t *testing.T) {
+	cells := cave(100, 100)
+	rects := Merge(cells)
+	checkCovered(t, cells, rects)
+	if len(rects) >= len(cells)/2 {
+		t.Errorf("%d cells were only merged into %d rectangles", len(cells), len(rects))
+	}
+}
+
+// checkCovered fails the test unless every cell is covered by exactly one rectangle, and the rectangles cover nothing else
+func checkCovered(t *testing.T, cells []Cell, rects []Rect) {
+	t.Helper()
+	covered := make(map[Cell]int)
+	for _, rect := range rects {
+		if rect.Width <= 0 || rect.Height <= 0 {
+			t.Errorf("%v is empty", rect)
+		}
+		for row := rect.Row; row < rect.Row+rect.Height; row++ {
+			for col := rect.Col; col < rect.Col+rect.Width; col++ {
+				covered[Cell{col, row}]++
+			}
+		}
+	}
+	solid := make(map[Cell]bool)
+	for _, cell := range cells {
+		solid[cell] = true
+		if covered[cell] != 1 {
+			t.Errorf("%v is covered %d times", cell, covered[cell])
+		}
+	}
+	for cell := range covered {
+		if !solid[cell] {
+			t.Errorf("%v is covered, but it isn't a cell", cell)
+		}
+	}
+}
+
+// cave generates a width by height map of solid cells that looks like a cave, the same every time
+func cave(width, height int) []Cell {
+	random := rand.New(rand.NewPCG(1, 2))
+	solid := make([][]bool, height)
+	for row := range solid {
+		solid[row] = make([]bool, width)
+		for col := range solid[row] {
+			solid[row][col] = random.Float64() < 0.45
+		}
+	}
+	// smooth it out, so there are walls and open spaces instead of noise
+	for range 4 {
+		next := make([][]bool, height)
+		for row := range next {
+			next[row] = make([]bool, width)
+			for col := range next[row] {
+				neighbours := 0
+				for dr := -1; dr <= 1; dr++ {
+					for dc := -1; dc <= 1; dc++ {
+						r, c := row+dr, col+dc
+						if r < 0 || r >= height || c < 0 || c >= width || solid[r][c] {
+							neighbours++
+						}
+					}
+				}
+				next[row][col] = neighbours >= 5
+			}
+		}
+		solid = next
+	}
+	var cells []Cell
+	for row := range solid {
+		for col := range solid[row] {
+			if solid[row][col] {
+				cells = append(cells, Cell{Col: col, Row: row})
+			}
+		}
+	}
+	return cells
+}
+
+// BenchmarkStep steps a space with bodies flying around a generated cave, with a box for every tile and with the tiles
+// merged into rectangles
+func BenchmarkStep(b *testing.B) {
+	const size = 150
+	cells := cave(size, size)
+	tiles := make([]Rect, 0, len(cells))
+	for _, cell := range cells {
+		tiles = append(tiles, Rect{Col: cell.Col, Row: cell.Row, Width: 1, Height: 1})
+	}
+	for _, walls := range []struct {
+		name  string
+		rects []Rect
+	}{
+		{"tiles", tiles},
+		{"merged", Merge(cells)},
+	} {
+		b.Run(fmt.Sprintf("%v-%d", walls.name, len(walls.rects)), func(b *testing.B) {
+			space := cp.NewSpace()
+			space.UseSpatialHash(1, 50)
+			for _, rect := range walls.rects {
+				space.AddShape(NewWall(rect, cp.Vector{}, DefaultMaterial).Shape)
+			}
+			random := rand.New(rand.NewPCG(3, 4))
+			for range 200 {
+				body := space.AddBody(cp.NewBody(1, cp.MomentForCircle(1, 0, 0.4, cp.Vector{})))
+				body.SetPosition(cp.Vector{X: random.Float64() * size, Y: random.Float64() * size})
+				body.SetVelocity(random.Float64()*20-10, random.Float64()*20-10)
+				shape := space.AddShape(cp.NewCircle(body, 0.4, cp.Vector{}))
+				shape.SetElasticity(1)
+			}
+			b.ResetTimer()
+			for range b.N {
+				space.Step(1.0 / 50)
+			}
+		})
+	}
+}
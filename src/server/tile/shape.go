@@ -0,0 +1,80 @@
+package tile
+
+import (
+	"Geomyidae/internal/constants"
+	"Geomyidae/internal/shared_structs"
+	"Geomyidae/internal/tiled"
+
+	"github.com/google/uuid"
+	"github.com/jakecoffman/cp/v2"
+)
+
+// Tiles with collision shapes drawn in Tiled, like slopes and half-height platforms, aren't merged.
+// Each one gets a static body with its shapes on it.
+
+// lineRadius is how thick polylines and the outlines of concave polygons are, in meters.
+// Lines with no thickness at all are easy to tunnel through.
+const lineRadius = 0.05
+
+// NewShaped makes the collision shapes of the tile at cell, offset by offset meters, out of material.
+// Like NewWall, it is added straight to the physics space: add every shape on its body.
+// Convex polygons are solid. Concave ones are only solid along their outline, like polylines.
+func NewShaped(cell Cell, offset cp.Vector, shapes []tiled.Shape, material Material) *shared_structs.GameObject {
+	body := cp.NewStaticBody()
+	body.SetPosition(cp.Vector{X: float64(cell.Col) + offset.X, Y: float64(cell.Row) + offset.Y})
+	for _, s := range shapes {
+		verts := make([]cp.Vector, len(s.Points))
+		for i, p := range s.Points {
+			verts[i] = cp.Vector{X: p.X, Y: p.Y}
+		}
+		switch {
+		case s.Kind == tiled.ShapeCircle:
+			addShape(body, cp.NewCircle(body, s.Radius, cp.Vector{X: s.Center.X, Y: s.Center.Y}), material)
+		case s.Kind == tiled.ShapePolygon && convex(verts):
+			addShape(body, cp.NewPolyShape(body, len(verts), verts, cp.NewTransformIdentity(), 0), material)
+		default:
+			if s.Kind == tiled.ShapePolygon {
+				verts = append(verts, verts[0]) // close the outline
+			}
+			for i := 1; i < len(verts); i++ {
+				addShape(body, cp.NewSegment(body, verts[i-1], verts[i], lineRadius), material)
+			}
+		}
+	}
+	obj := &shared_structs.GameObject{
+		UUID:     uuid.New().String(),
+		Body:     body,
+		IsStatic: true,
+		Identity: constants.Tile,
+		Damage:   material.Damage,
+	}
+	body.EachShape(func(shape *cp.Shape) {
+		if obj.Shape == nil {
+			obj.Shape = shape
+		}
+	})
+	body.UserData = obj
+	return obj
+}
+
+func addShape(body *cp.Body, shape *cp.Shape, material Material) {
+	material.apply(shape)
+	body.AddShape(shape)
+}
+
+// convex reports whether the polygon turns the same way at every corner
+func convex(verts []cp.Vector) bool {
+	var sign float64
+	for i := range verts {
+		a, b, c := verts[i], verts[(i+1)%len(verts)], verts[(i+2)%len(verts)]
+		cross := b.Sub(a).Cross(c.Sub(b))
+		if cross == 0 {
+			continue
+		}
+		if sign != 0 && (cross > 0) != (sign > 0) {
+			return false
+		}
+		sign = cross
+	}
+	return true
+}
@@ -3,10 +3,12 @@ package tile
 import (
 	"Geomyidae/internal/constants"
 	"Geomyidae/internal/shared_structs"
-	"Geomyidae/server/tracker"
-	"Geomyidae/server/turret"
-	"time"
+	"Geomyidae/server/collision"
+	"Geomyidae/server/entity"
+	"Geomyidae/server/message"
+	"log"
 
+	"github.com/google/uuid"
 	"github.com/jakecoffman/cp/v2"
 )
 
@@ -15,45 +17,155 @@ type Tile struct {
 	*shared_structs.GameObject
 }
 
+// Action is one step of an action sequence: wait Seconds, then spawn Count objects of Type at X, Y.
+// Anything that is registered with the entity package can be spawned. Enemies go after whoever set off the sequence.
 type Action struct {
-	Seconds int
-	Type    constants.UserDataCode
-	X       float64
-	Y       float64
+	Seconds    float64
+	Type       constants.UserDataCode
+	X          float64
+	Y          float64
+	Count      int               // 0 is the same as 1
+	Properties map[string]string // for entity.Params
+}
+
+// Trigger is a trigger tile as placed on the map, with the sequence it sets off
+type Trigger struct {
+	X                    float64 // the middle of the tile, in meters
+	Y                    float64
+	Sprite               string
+	SpriteOffsetX        int
+	SpriteOffsetY        int
+	SpriteWidth          int
+	SpriteHeight         int
+	SpriteFlipHorizontal bool
+	SpriteFlipVertical   bool
+	SpriteFlipDiagonal   bool
+	Actions              []Action
+}
+
+// NewTrigger makes the tile for a Trigger. It sets off its sequence the first time a player touches it.
+func NewTrigger(trigger Trigger) *Tile {
+	body := cp.NewStaticBody()
+	shape := cp.NewBox(body, 1, 1, 0)
+	shape.SetElasticity(0.25)
+	shape.SetDensity(0.5)
+	shape.SetFriction(1.0)
+	body.AddShape(shape)
+	body.SetPosition(cp.Vector{X: trigger.X, Y: trigger.Y})
+
+	obj := NewTile(
+		&shared_structs.GameObject{
+			Sprite:               trigger.Sprite,
+			SpriteOffsetX:        trigger.SpriteOffsetX,
+			SpriteOffsetY:        trigger.SpriteOffsetY,
+			SpriteWidth:          trigger.SpriteWidth,
+			SpriteHeight:         trigger.SpriteHeight,
+			SpriteFlipHorizontal: trigger.SpriteFlipHorizontal,
+			SpriteFlipVertical:   trigger.SpriteFlipVertical,
+			SpriteFlipDiagonal:   trigger.SpriteFlipDiagonal,
+			Angle:                shared_structs.RoundedFloat2(body.Angle()),
+			UUID:                 uuid.New().String(),
+			Body:                 body,
+			Shape:                shape,
+			IsStatic:             true,
+			Identity:             constants.Tile,
+		}, trigger.Actions)
+	body.UserData = obj.GameObject
+	return obj
 }
 
 func NewTile(gameObject *shared_structs.GameObject, seq []Action) *Tile {
 	return &Tile{seq, gameObject}
 }
 
-func (t *Tile) ApplyBehavior(deltaTime float64, spawnerPipeline chan shared_structs.HasBehavior) {
-	t.Body.EachArbiter(func(arbiter *cp.Arbiter) {
-		_, bodB := arbiter.Bodies()
-		if ptr, ok := bodB.UserData.(*shared_structs.GameObject); ok {
-			if ptr.Identity == constants.Player {
-				if t.ActionSequence != nil {
-					go func() {
-						for _, action := range t.ActionSequence {
-							time.Sleep(time.Duration(action.Seconds) * time.Second)
-							var obj shared_structs.HasBehavior
-							if action.Type == constants.Turret {
-								obj = turret.NewTurret(ptr, action.X, action.Y)
-							} else if action.Type == constants.Tracker {
-								obj = tracker.NewTracker(ptr, action.X, action.Y)
-							}
-							if obj != nil {
-								spawnerPipeline <- obj
-							}
-						}
-					}()
-					t.Delete = true
-				}
-			}
-		}
-	})
+func (t *Tile) ApplyBehavior(tick shared_structs.Tick, spawnerPipeline chan shared_structs.HasBehavior) {
 	return
 }
 
+// Triggered is the collision rule for a player touching a tile. If it is a trigger, it sets off its sequence, once.
+// It runs every step they touch, so a trigger that couldn't set off its sequence straight away tries again.
+func Triggered(c collision.Contact) {
+	t, ok := c.EntityB.(*Tile)
+	if !ok || t.ActionSequence == nil || t.Delete {
+		return
+	}
+	select {
+	case c.Spawn <- NewSequence(c.A, t.ActionSequence):
+		t.Delete = true
+		message.SendEveryone(c.Tick, t.UUID, message.TriggerFired{Player: c.A.UUID})
+	default:
+	}
+}
+
 func (t *Tile) GetObject() *shared_structs.GameObject {
 	return t.GameObject
 }
+
+// Sequence plays out an action sequence after its tile has been triggered.
+// It has no body, so it is never simulated by physics or sent to clients, it just counts down ticks.
+type Sequence struct {
+	*shared_structs.GameObject
+	target  *shared_structs.GameObject
+	actions []Action
+	wait    float64
+	spawned int // how many of the current action have been spawned
+}
+
+func NewSequence(target *shared_structs.GameObject, actions []Action) *Sequence {
+	seq := &Sequence{
+		GameObject: &shared_structs.GameObject{
+			UUID:     uuid.New().String(),
+			IsStatic: true,
+			Identity: constants.Sequence,
+		},
+		target:  target,
+		actions: actions,
+	}
+	if len(actions) > 0 {
+		seq.wait = actions[0].Seconds
+	}
+	return seq
+}
+
+func (s *Sequence) ApplyBehavior(tick shared_structs.Tick, spawnerPipeline chan shared_structs.HasBehavior) {
+	if len(s.actions) == 0 {
+		s.Delete = true
+		return
+	}
+	s.wait -= tick.DeltaTime
+	if s.wait > 0 {
+		return
+	}
+	action := s.actions[0]
+	obj, err := entity.New(action.Type, entity.Params{
+		X:          action.X,
+		Y:          action.Y,
+		Target:     s.target,
+		Properties: action.Properties,
+	})
+	if err != nil {
+		log.Printf("sequence can't spawn: %v", err)
+	}
+	if obj != nil {
+		select {
+		case spawnerPipeline <- obj:
+		default:
+			// try again next tick
+			return
+		}
+	}
+	s.spawned++
+	if s.spawned < max(action.Count, 1) {
+		// one at a time, the rest follow on the next ticks
+		return
+	}
+	s.spawned = 0
+	s.actions = s.actions[1:]
+	if len(s.actions) > 0 {
+		s.wait = s.actions[0].Seconds
+	}
+}
+
+func (s *Sequence) GetObject() *shared_structs.GameObject {
+	return s.GameObject
+}
@@ -3,6 +3,10 @@ package tracker
 import (
 	"Geomyidae/internal/constants"
 	"Geomyidae/internal/shared_structs"
+	"Geomyidae/server/collision"
+	"Geomyidae/server/combat"
+	"Geomyidae/server/entity"
+	"errors"
 	"math"
 
 	"github.com/google/uuid"
@@ -11,42 +15,42 @@ import (
 
 type Tracker struct {
 	*shared_structs.GameObject
-	target *shared_structs.GameObject
+	target   *shared_structs.GameObject
+	retarget func() *shared_structs.GameObject
+	thrust   float64 // even infinitesimal thrust gets fast quick
 }
 
 func NewTracker(target *shared_structs.GameObject, x, y float64) *Tracker {
-	body := cp.NewBody(1, 1)
-	shape := cp.NewBox(body, 1, 1, 0)
-	shape.SetElasticity(0.25)
-	shape.SetDensity(0.5)
-	shape.SetFriction(1.0)
-	body.AddShape(shape)
-	body.SetPosition(cp.Vector{X: x, Y: y})
+	archetype := entity.ArchetypeOf(string(constants.Tracker))
+	body, shape := archetype.NewBody(x, y)
 	obj := shared_structs.GameObject{
-		Sprite:               "spaceShooterRedux",
-		SpriteOffsetX:        450,
-		SpriteOffsetY:        0,
-		SpriteWidth:          98,
-		SpriteHeight:         75,
-		SpriteFlipHorizontal: false,
-		SpriteFlipVertical:   true,
-		SpriteFlipDiagonal:   false,
-		Angle:                0,
-		UUID:                 uuid.New().String(),
-		Body:                 body,
-		Shape:                shape,
-		Identity:             constants.Tracker,
+		Angle:    0,
+		UUID:     uuid.New().String(),
+		Body:     body,
+		Shape:    shape,
+		Identity: constants.Tracker,
+		Damage:   archetype.Damage, // hit points it takes from the player it crashes into
+		Team:     combat.TeamEnemies,
 	}
+	archetype.Dress(&obj)
 	body.UserData = &obj
 
-	return &Tracker{&obj, target}
+	return &Tracker{GameObject: &obj, target: target, thrust: archetype.Speed}
 }
 
-// even infinitesimal thrust gets fast quick
-const thrust = 0.0001
-
-func (t *Tracker) ApplyBehavior(deltaTime float64, spawnerPipeline chan shared_structs.HasBehavior) {
-	tr := thrust * deltaTime
+func (t *Tracker) ApplyBehavior(tick shared_structs.Tick, spawnerPipeline chan shared_structs.HasBehavior) {
+	if t.target.Delete {
+		if t.retarget == nil {
+			t.Delete = true
+			return
+		}
+		next := t.retarget()
+		if next == nil {
+			return
+		}
+		t.target = next
+	}
+	tr := t.thrust * tick.DeltaTime
 	tpos := t.target.Body.Position()
 	pos := t.Body.Position()
 	angle := math.Atan2(tpos.Y-pos.Y, tpos.X-pos.X)
@@ -55,19 +59,40 @@ func (t *Tracker) ApplyBehavior(deltaTime float64, spawnerPipeline chan shared_s
 		X: -math.Sin(tr),
 		Y: -math.Cos(-tr),
 	}, cp.Vector{X: 0, Y: 0})
-	if t.target.Delete {
-		t.Delete = true
+}
+
+// Shot is the collision rule for a bullet hitting a tracker. One hit destroys it.
+func Shot(c collision.Contact) {
+	if c.A.Delete || !c.Rules.CanHurt(c.B, c.A) {
+		return
 	}
-	t.Body.EachArbiter(func(arbiter *cp.Arbiter) {
-		_, bodB := arbiter.Bodies()
-		if ptr, ok := bodB.UserData.(*shared_structs.GameObject); ok {
-			if ptr.Identity == constants.Player || ptr.Identity == constants.Bullet {
-				t.Delete = true
-			}
-		}
-	})
+	combat.ReportHit(c.B, c.A, c.Tick)
+	combat.Report(combat.Kill{Tick: c.Tick.Number, Victim: c.A.UUID, VictimType: c.A.Identity, Killer: combat.OwnerOf(c.B)})
+	c.A.Delete = true
+}
+
+// Crashed is the collision rule for a tracker running into a player.
+// It blows up on impact, so it killed itself and nobody gets the credit. The player takes its damage, see player.Hurt.
+func Crashed(c collision.Contact) {
+	if c.A.Delete {
+		return
+	}
+	combat.Report(combat.Kill{Tick: c.Tick.Number, Victim: c.A.UUID, VictimType: c.A.Identity, Killer: c.A.UUID})
+	c.A.Delete = true
 }
 
 func (t *Tracker) GetObject() *shared_structs.GameObject {
 	return t.GameObject
 }
+
+func init() {
+	entity.RequiresPositive(string(constants.Tracker), "speed")
+	entity.Register(constants.Tracker, func(params entity.Params) (shared_structs.HasBehavior, error) {
+		if params.Target == nil {
+			return nil, errors.New("it needs a target to chase")
+		}
+		t := NewTracker(params.Target, params.X, params.Y)
+		t.retarget = params.Retarget
+		return t, nil
+	})
+}
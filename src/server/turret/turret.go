@@ -4,52 +4,63 @@ import (
 	"Geomyidae/internal/constants"
 	"Geomyidae/internal/shared_structs"
 	"Geomyidae/server/bullet"
-	"Geomyidae/server/pickup"
+	"Geomyidae/server/collision"
+	"Geomyidae/server/combat"
+	"Geomyidae/server/entity"
+	"errors"
 	"math"
-	"time"
 
 	"github.com/google/uuid"
-	"github.com/jakecoffman/cp/v2"
 )
 
 type Turret struct {
 	*shared_structs.GameObject
 	target    *shared_structs.GameObject
-	shootTime time.Time
+	retarget  func() *shared_structs.GameObject
+	shootTime float64
+	archetype entity.Archetype
 }
 
-func (t *Turret) ApplyBehavior(deltaTime float64, spawnerPipeline chan shared_structs.HasBehavior) {
+func (t *Turret) ApplyBehavior(tick shared_structs.Tick, spawnerPipeline chan shared_structs.HasBehavior) {
+	if t.target.Delete {
+		if t.retarget == nil {
+			t.Delete = true
+			return
+		}
+		next := t.retarget()
+		if next == nil {
+			return
+		}
+		t.target = next
+	}
 	tpos := t.target.Body.Position()
 	pos := t.Body.Position()
 	angle := math.Atan2(tpos.Y-pos.Y, tpos.X-pos.X)
 	// the fact that I have to add half a pi of radians makes me very suspicious that we are converting angles incorrectly
 	// probably in the client?
 	t.Body.SetAngle(angle + (math.Pi / 2))
-	if t.shootTime.UnixMilli() < time.Now().UnixMilli() {
+	t.shootTime -= tick.DeltaTime
+	if t.shootTime <= 0 {
 		newBullet := bullet.NewBullet(t.GetObject())
 		select {
 		case spawnerPipeline <- newBullet:
 		default:
 		}
-		t.shootTime = time.Now().Add(time.Second * 5)
+		t.shootTime = t.archetype.Cooldown
 	}
-	if t.target.Delete {
-		t.Delete = true
-	}
-	t.Body.EachArbiter(func(arbiter *cp.Arbiter) {
-		_, bodB := arbiter.Bodies()
-		if ptr, ok := bodB.UserData.(*shared_structs.GameObject); ok {
-			if ptr.Identity == constants.Bullet {
+}
 
-				newPickup := pickup.NewPickup(pos.X, pos.Y, "bombplus")
-				select {
-				case spawnerPipeline <- newPickup:
-				default:
-				}
-				t.Delete = true
-			}
-		}
-	})
+// Shot is the collision rule for a bullet hitting a turret. One hit destroys it, and it drops its drops.
+func Shot(c collision.Contact) {
+	t := c.EntityA.(*Turret)
+	if t.Delete || !c.Rules.CanHurt(c.B, t.GameObject) {
+		return
+	}
+	combat.ReportHit(c.B, t.GameObject, c.Tick)
+	combat.Report(combat.Kill{Tick: c.Tick.Number, Victim: t.UUID, VictimType: t.Identity, Killer: combat.OwnerOf(c.B)})
+	pos := t.Body.Position()
+	t.archetype.SpawnDrops(pos.X, pos.Y, c.Spawn)
+	t.Delete = true
 }
 
 func (t *Turret) GetObject() *shared_structs.GameObject {
@@ -57,28 +68,28 @@ func (t *Turret) GetObject() *shared_structs.GameObject {
 }
 
 func NewTurret(target *shared_structs.GameObject, x, y float64) *Turret {
-	body := cp.NewBody(1, 1)
-	shape := cp.NewBox(body, 1, 1, 0)
-	shape.SetElasticity(0.25)
-	shape.SetDensity(0.5)
-	shape.SetFriction(1.0)
-	body.AddShape(shape)
-	body.SetPosition(cp.Vector{X: x, Y: y})
+	archetype := entity.ArchetypeOf(string(constants.Turret))
+	body, shape := archetype.NewBody(x, y)
 	obj := shared_structs.GameObject{
-		Sprite:               "spaceShooterRedux",
-		SpriteOffsetX:        225,
-		SpriteOffsetY:        0,
-		SpriteWidth:          98,
-		SpriteHeight:         75,
-		SpriteFlipHorizontal: false,
-		SpriteFlipVertical:   true,
-		SpriteFlipDiagonal:   false,
-		Angle:                0,
-		UUID:                 uuid.New().String(),
-		Body:                 body,
-		Shape:                shape,
-		Identity:             constants.Turret,
+		Angle:    0,
+		UUID:     uuid.New().String(),
+		Body:     body,
+		Shape:    shape,
+		Identity: constants.Turret,
+		Team:     combat.TeamEnemies,
 	}
+	archetype.Dress(&obj)
 	body.UserData = &obj
-	return &Turret{&obj, target, time.Now().Add(time.Second * 5)}
+	return &Turret{GameObject: &obj, target: target, shootTime: archetype.Cooldown, archetype: archetype}
+}
+
+func init() {
+	entity.Register(constants.Turret, func(params entity.Params) (shared_structs.HasBehavior, error) {
+		if params.Target == nil {
+			return nil, errors.New("it needs a target to aim at")
+		}
+		t := NewTurret(params.Target, params.X, params.Y)
+		t.retarget = params.Retarget
+		return t, nil
+	})
 }